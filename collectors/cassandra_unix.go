@@ -7,7 +7,6 @@ import (
 
 	"github.com/oliveagle/go-collectors/datapoint"
 	"github.com/oliveagle/go-collectors/metadata"
-	"github.com/oliveagle/go-collectors/util"
 )
 
 func init() {
@@ -17,7 +16,7 @@ func init() {
 func c_nodestats_cfstats_linux() (datapoint.MultiDataPoint, error) {
 	var md datapoint.MultiDataPoint
 	var keyspace, table string
-	util.ReadCommand(func(line string) error {
+	readCommand(func(line string) error {
 		fields := strings.Split(strings.TrimSpace(line), ": ")
 		if len(fields) != 2 {
 			return nil
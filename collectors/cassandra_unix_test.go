@@ -0,0 +1,13 @@
+// +build darwin linux
+
+package collectors
+
+import (
+	"testing"
+)
+
+func Test_c_nodestats_cfstats_linux_golden(t *testing.T) {
+	fx := loadFixture(t, "c_nodestats_cfstats_linux")
+	md, err := c_nodestats_cfstats_linux()
+	fx.check(t, md, err)
+}
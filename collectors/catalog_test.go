@@ -0,0 +1,117 @@
+package collectors
+
+import (
+	"bufio"
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// metadataAllowlist lists the Add and AddTS calls that are known to emit
+// metrics with an unknown rate or unit, keyed as "file: metric expression".
+// Remove entries from it when documenting the metrics they refer to.
+const metadataAllowlist = "testdata/metadata_allowlist.txt"
+
+// undocumentedMetrics finds all Add and AddTS calls of the collectors in
+// this directory, for all operating systems, that pass metadata.Unknown or
+// metadata.None.
+func undocumentedMetrics(t *testing.T) map[string]bool {
+	fset := token.NewFileSet()
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := make(map[string]bool)
+	for _, name := range files {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, name, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			fn, ok := call.Fun.(*ast.Ident)
+			if !ok {
+				return true
+			}
+			var rate, unit int
+			switch {
+			case fn.Name == "Add" && len(call.Args) == 7:
+				rate, unit = 4, 5
+			case fn.Name == "AddTS" && len(call.Args) == 8:
+				rate, unit = 5, 6
+			default:
+				return true
+			}
+			if !isSelector(call.Args[rate], "metadata", "Unknown") && !isSelector(call.Args[unit], "metadata", "None") {
+				return true
+			}
+			var b bytes.Buffer
+			printer.Fprint(&b, fset, call.Args[1])
+			found[name+": "+b.String()] = true
+			return true
+		})
+	}
+	return found
+}
+
+func isSelector(e ast.Expr, pkg, name string) bool {
+	s, ok := e.(*ast.SelectorExpr)
+	if !ok || s.Sel.Name != name {
+		return false
+	}
+	x, ok := s.X.(*ast.Ident)
+	return ok && x.Name == pkg
+}
+
+func TestMetadataAllowlist(t *testing.T) {
+	f, err := os.Open(metadataAllowlist)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	allowed := make(map[string]bool)
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		allowed[line] = true
+	}
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+	found := undocumentedMetrics(t)
+	var missing, stale []string
+	for k := range found {
+		if !allowed[k] {
+			missing = append(missing, k)
+		}
+	}
+	for k := range allowed {
+		if !found[k] {
+			stale = append(stale, k)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(stale)
+	for _, k := range missing {
+		t.Errorf("metric without rate or unit: %s", k)
+	}
+	for _, k := range stale {
+		t.Errorf("stale entry in %s: %s", metadataAllowlist, k)
+	}
+}
@@ -1,8 +1,8 @@
 package collectors
 
 import (
-	"bufio"
-	"os"
+	"fmt"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -11,6 +11,8 @@ import (
 
 	"github.com/oliveagle/go-collectors/datapoint"
 	"github.com/oliveagle/go-collectors/metadata"
+	"github.com/oliveagle/go-collectors/pipeline"
+	"github.com/oliveagle/go-collectors/slog"
 	"github.com/oliveagle/go-collectors/util"
 )
 
@@ -22,6 +24,26 @@ type Collector interface {
 	Init()
 }
 
+// A RemoteCollector polls another host, such as an SNMP device, and sets the
+// host tag of its datapoints itself. The local host tag that Add gives to
+// datapoints without one is removed from them instead of attributing the
+// remote metrics to this host.
+type RemoteCollector interface {
+	Collector
+	Remote() bool
+}
+
+// removeLocalHost removes the local host tag from the datapoints of a remote
+// collector.
+func removeLocalHost(md datapoint.MultiDataPoint) {
+	host := util.Host()
+	for _, dp := range md {
+		if h, _ := dp.Tags.Get("host"); h == host {
+			dp.Tags = dp.Tags.Without("host")
+		}
+	}
+}
+
 const (
 	osCPU          = "os.cpu"
 	osDiskFree     = "os.disk.fs.space_free"
@@ -62,26 +84,42 @@ var (
 	// specified.
 	DefaultFreq = time.Second * 15
 
-	timestamp = time.Now().Unix()
-	tlock     sync.Mutex
-	AddTags   datapoint.TagSet
+	// Clock stamps datapoints and schedules collector runs. Tests may
+	// replace it with a util.FakeClock.
+	Clock util.Clock = util.SystemClock
+
+	AddTags datapoint.TagSet
+
+	// Pipeline is applied to the datapoints of every collector run before
+	// they are sent.
+	Pipeline pipeline.Pipeline
+
+	// defaults caches AddTags and the host tag as Tags, which all
+	// datapoints share unless they override them.
+	defaults struct {
+		sync.Mutex
+		host string
+		add  datapoint.TagSet
+		tags datapoint.Tags
+	}
 )
 
-func init() {
-	go func() {
-		for t := range time.Tick(time.Second) {
-			tlock.Lock()
-			timestamp = t.Unix()
-			tlock.Unlock()
-		}
-	}()
+// defaultTags returns AddTags with the host tag set to util.Host(). They are
+// only rebuilt if either changed.
+func defaultTags() datapoint.Tags {
+	defaults.Lock()
+	defer defaults.Unlock()
+	if host := util.Host(); defaults.add == nil || defaults.host != host || !defaults.add.Equal(AddTags) {
+		defaults.host = host
+		defaults.add = AddTags.Copy()
+		defaults.tags = datapoint.NewTags(AddTags, datapoint.TagSet{"host": host})
+	}
+	return defaults.tags
 }
 
-func now() (t int64) {
-	tlock.Lock()
-	t = timestamp
-	tlock.Unlock()
-	return
+// now returns the Unix time of Clock.
+func now() int64 {
+	return Clock.Now().Unix()
 }
 
 // Search returns all collectors matching the pattern s.
@@ -98,7 +136,10 @@ func Search(s string) []Collector {
 	return r
 }
 
-// Run runs specified collectors. Use nil for all collectors.
+// Run runs specified collectors. Use nil for all collectors. The Tickers
+// of Pipeline are ticked every DefaultFreq and their datapoints sent with
+// those of the collectors; Pipeline.Flush returns the datapoints held back
+// at shutdown.
 func Run(cs []Collector) chan *datapoint.DataPoint {
 	if cs == nil {
 		cs = collectors
@@ -107,59 +148,127 @@ func Run(cs []Collector) chan *datapoint.DataPoint {
 	for _, c := range cs {
 		go c.Run(ch)
 	}
+	go tickPipeline(ch)
 	return ch
 }
 
+// tickPipeline ticks Pipeline every DefaultFreq.
+func tickPipeline(dpchan chan<- *datapoint.DataPoint) {
+	for {
+		<-Clock.After(DefaultFreq)
+		for _, dp := range Pipeline.Tick(Clock.Now()) {
+			dpchan <- dp
+		}
+	}
+}
+
+// Once runs c a single time and returns the datapoints it produced. Disabled
+// IntervalCollectors return no datapoints.
+func Once(c Collector) (datapoint.MultiDataPoint, error) {
+	switch c := c.(type) {
+	case *IntervalCollector:
+		if c.Enable != nil && !c.Enable() {
+			return nil, nil
+		}
+		md, err := c.F()
+		if c.Remote() {
+			removeLocalHost(md)
+		}
+		return md, err
+	case *ProgramCollector:
+		var md datapoint.MultiDataPoint
+		ch := make(chan *datapoint.DataPoint)
+		done := make(chan error, 1)
+		go func() {
+			done <- c.runProgram(ch)
+			close(ch)
+		}()
+		for dp := range ch {
+			md = append(md, dp)
+		}
+		return md, <-done
+	}
+	return nil, fmt.Errorf("collectors: cannot run %s once", c.Name())
+}
+
+// logger returns a slog.Context that adds the name of c as the collector
+// field to every message. Errors returned by collectors and the output of
+// program collectors are logged with it.
+func logger(c Collector) *slog.Context {
+	return slog.With(slog.F("collector", c.Name()))
+}
+
 // AddTS is the same as Add but lets you specify the timestamp
 func AddTS(md *datapoint.MultiDataPoint, name string, ts int64, value interface{}, t datapoint.TagSet, rate metadata.RateType, unit metadata.Unit, desc string) {
-	tags := t.Copy()
 	if rate != metadata.Unknown {
-		metadata.AddMeta(name, nil, "rate", rate, false)
+		addMeta(name, nil, "rate", rate)
 	}
 	if unit != metadata.None {
-		metadata.AddMeta(name, nil, "unit", unit, false)
+		addMeta(name, nil, "unit", unit)
 	}
 	if desc != "" {
-		metadata.AddMeta(name, tags, "desc", desc, false)
+		addMeta(name, t, "desc", desc)
 	}
-	if host, present := tags["host"]; !present {
-		tags["host"] = util.Hostname
-	} else if host == "" {
-		delete(tags, "host")
+	d, err := datapoint.NewDataPoint(name, time.Unix(ts, 0), value, defaultTags().Merge(t))
+	if err != nil {
+		dropped.Errorf(slog.With(), "dropping datapoint of %s: %v", name, err)
+		return
+	}
+	*md = append(*md, d)
+}
+
+// dropped limits the logs of datapoints dropped by AddTS, which may repeat
+// for every datapoint of every run: the first is logged and the others are
+// summarized once per DefaultErrorInterval.
+var dropped = newErrorLimiter(0)
+
+// metaAdded holds when the metadata of datapoints was last passed to
+// metadata.AddMeta, by metaAddKey.
+var metaAdded sync.Map
+
+// metaAddKey identifies metadata by the tag keys rather than the tags of
+// datapoints, so that a description is added once for all the series of a
+// metric that share it.
+type metaAddKey struct {
+	metric, tagKeys, name string
+	value                 interface{}
+}
+
+// addMeta passes the metadata of a datapoint to metadata.AddMeta the first
+// time it is seen, and again before it expires, rather than for every
+// datapoint.
+func addMeta(metric string, t datapoint.TagSet, name string, value interface{}) {
+	k := metaAddKey{metric: metric, name: name, value: value}
+	if len(t) > 0 {
+		keys := make([]string, 0, len(t))
+		for key := range t {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		k.tagKeys = strings.Join(keys, ",")
 	}
-	tags = AddTags.Copy().Merge(tags)
-	d := datapoint.DataPoint{
-		Metric:    name,
-		Timestamp: ts,
-		Value:     value,
-		Tags:      tags,
+	now := Clock.Now()
+	if added, ok := metaAdded.Load(k); ok && now.Sub(added.(time.Time)) < metadata.Expiry/2 {
+		return
 	}
-	*md = append(*md, &d)
+	metaAdded.Store(k, now)
+	metadata.AddMeta(metric, t, name, value, false)
 }
 
 // Add appends a new data point with given metric name, value, and tags. Tags
 // may be nil. If tags is nil or does not contain a host key, it will be
 // automatically added. If the value of the host key is the empty string, it
 // will be removed (use this to prevent the normal auto-adding of the host tag).
+// t is neither modified nor retained, so callers may reuse it.
+//
+// value may be a datapoint.Value or anything datapoint.ParseValue accepts;
+// it is converted to a datapoint.Value. Datapoints whose value cannot be
+// parsed, such as NaN, are dropped; drops are logged like repeated collector
+// errors.
 func Add(md *datapoint.MultiDataPoint, name string, value interface{}, t datapoint.TagSet, rate metadata.RateType, unit metadata.Unit, desc string) {
 	AddTS(md, name, now(), value, t, rate, unit, desc)
 }
 
-func readLine(fname string, line func(string) error) error {
-	f, err := os.Open(fname)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		if err := line(scanner.Text()); err != nil {
-			return err
-		}
-	}
-	return scanner.Err()
-}
-
 // IsDigit returns true if s consists of decimal digits.
 func IsDigit(s string) bool {
 	r := strings.NewReader(s)
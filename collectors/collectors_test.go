@@ -1,6 +1,14 @@
 package collectors
 
-import "testing"
+import (
+	"testing"
+	"time"
+
+	"github.com/oliveagle/go-collectors/datapoint"
+	"github.com/oliveagle/go-collectors/metadata"
+	"github.com/oliveagle/go-collectors/pipeline"
+	"github.com/oliveagle/go-collectors/util"
+)
 
 func TestIsDigit(t *testing.T) {
 	if IsDigit("1a3") {
@@ -10,3 +18,85 @@ func TestIsDigit(t *testing.T) {
 		t.Error("029: expected true")
 	}
 }
+
+// BenchmarkAdd adds the per index datapoints of c_elasticsearch_indices.
+func BenchmarkAdd(b *testing.B) {
+	ts := datapoint.TagSet{"index_name": "logstash-2015.03.10", "cluster": "es01"}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var md datapoint.MultiDataPoint
+		for j := 0; j < 20; j++ {
+			Add(&md, "elastic.indices.docs.count", j, ts, metadata.Gauge, metadata.Document, "")
+		}
+	}
+}
+
+func TestRemoteCollector(t *testing.T) {
+	c := &IntervalCollector{
+		F: func() (datapoint.MultiDataPoint, error) {
+			var md datapoint.MultiDataPoint
+			Add(&md, "cisco.cpu", 1, datapoint.TagSet{"host": "router1"}, metadata.Unknown, metadata.None, "")
+			Add(&md, "cisco.mem.used", 0, nil, metadata.Unknown, metadata.None, "")
+			return md, nil
+		},
+		remote: true,
+	}
+	md, err := Once(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(md) != 2 || md[0].Tags.String() != "{host=router1}" || md[1].Tags.Len() != 0 {
+		t.Errorf("got %v", md)
+	}
+	c.remote = false
+	if md, _ := Once(c); md[1].Tags.String() != "{host="+util.Host()+"}" {
+		t.Errorf("got %v", md[1])
+	}
+}
+
+// tickCounter is a pipeline.Ticker that emits the times it was ticked at.
+type tickCounter struct{ pipeline.Stage }
+
+func (tickCounter) Tick(now time.Time) datapoint.MultiDataPoint {
+	return datapoint.MultiDataPoint{{Metric: "test.tick", Timestamp: now, Value: 1}}
+}
+
+func TestTickPipeline(t *testing.T) {
+	clock := util.NewFakeClock(fixtureTime)
+	setClock(t, clock)
+	prev := Pipeline
+	Pipeline = pipeline.Pipeline{tickCounter{pipeline.StageFunc(func(_ string, md datapoint.MultiDataPoint) datapoint.MultiDataPoint { return md })}}
+	t.Cleanup(func() { Pipeline = prev })
+	ch := make(chan *datapoint.DataPoint)
+	go tickPipeline(ch)
+	clock.BlockUntil(1)
+	clock.Advance(DefaultFreq)
+	if dp := <-ch; !dp.Timestamp.Equal(fixtureTime.Add(DefaultFreq)) {
+		t.Errorf("got %v", dp)
+	}
+}
+
+// TestAddMetaPerShape checks that descriptions are added once for the
+// series of a metric with the same tag keys, and again before they expire.
+func TestAddMetaPerShape(t *testing.T) {
+	clock := util.NewFakeClock(time.Unix(0, 0))
+	setClock(t, clock)
+	var md datapoint.MultiDataPoint
+	desc := func(port string) bool {
+		_, ok := metadata.Lookup("test.addmeta", datapoint.TagSet{"port": port}, "desc")
+		return ok
+	}
+	Add(&md, "test.addmeta", 1, datapoint.TagSet{"port": "1"}, metadata.Gauge, metadata.None, "Test.")
+	Add(&md, "test.addmeta", 1, datapoint.TagSet{"port": "2"}, metadata.Gauge, metadata.None, "Test.")
+	if !desc("1") || desc("2") {
+		t.Errorf("expected the description of the first series only")
+	}
+	if v, ok := metadata.Lookup("test.addmeta", nil, "rate"); !ok || v != metadata.RateType(metadata.Gauge) {
+		t.Errorf("got rate %v", v)
+	}
+	clock.Advance(metadata.Expiry / 2)
+	Add(&md, "test.addmeta", 1, datapoint.TagSet{"port": "3"}, metadata.Gauge, metadata.None, "Test.")
+	if !desc("3") {
+		t.Errorf("expected the description to be added again")
+	}
+}
@@ -0,0 +1,26 @@
+package collectors
+
+import (
+	"context"
+	"time"
+
+	"github.com/oliveagle/go-collectors/util"
+)
+
+// Commands runs the external programs of collectors. Tests replace it with
+// a util.FixtureRunner of recorded outputs.
+var Commands util.Runner = util.ExecRunner{}
+
+// readCommand runs name with args through Commands and calls line for each
+// line of its stdout. As with util.ReadCommand, the program is stopped after
+// 10 seconds.
+func readCommand(line func(string) error, name string, arg ...string) error {
+	return readCommandTimeout(time.Second*10, line, name, arg...)
+}
+
+// readCommandTimeout is the same as readCommand with a specifiable timeout.
+func readCommandTimeout(timeout time.Duration, line func(string) error, name string, arg ...string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return Commands.ReadCommand(ctx, line, name, arg...)
+}
@@ -1,7 +1,6 @@
 package collectors
 
 import (
-	"os"
 	"strconv"
 	"strings"
 
@@ -20,9 +19,12 @@ const (
 )
 
 func conntrackEnable() bool {
-	f, err := os.Open(conntrackCount)
-	defer f.Close()
-	return err == nil
+	f, err := openFile(conntrackCount)
+	if err != nil {
+		return false
+	}
+	f.Close()
+	return true
 }
 
 func c_conntrack_linux() (datapoint.MultiDataPoint, error) {
@@ -19,3 +19,12 @@ func Test_c_conntrack_linux(t *testing.T) {
 	// }
 	// t.Error("hh")
 }
+
+func Test_c_conntrack_linux_golden(t *testing.T) {
+	fx := loadFixture(t, "c_conntrack_linux")
+	if !conntrackEnable() {
+		t.Fatal("expected conntrack to be enabled")
+	}
+	md, err := c_conntrack_linux()
+	fx.check(t, md, err)
+}
@@ -2,6 +2,8 @@ package collectors
 
 import (
 	"testing"
+
+	"github.com/oliveagle/go-collectors/datapoint"
 )
 
 func Test_c_cpu_windows(t *testing.T) {
@@ -14,7 +16,7 @@ func Test_c_cpu_windows(t *testing.T) {
 	isOsCPUOk := false
 	for idx := range md {
 		t.Log(md[idx])
-		if md[idx].Metric == osCPU && md[idx].Value.(uint64) > 0 {
+		if md[idx].Metric == osCPU && md[idx].Value.(datapoint.Value).Float64() > 0 {
 			isOsCPUOk = true
 		}
 	}
@@ -8,7 +8,6 @@ import (
 
 	"github.com/oliveagle/go-collectors/datapoint"
 	"github.com/oliveagle/go-collectors/metadata"
-	"github.com/oliveagle/go-collectors/util"
 )
 
 func init() {
@@ -263,7 +262,7 @@ func severity(s string) int {
 
 func readOmreport(f func([]string), args ...string) {
 	args = append(args, "-fmt", "ssv")
-	_ = util.ReadCommand(func(line string) error {
+	_ = readCommand(func(line string) error {
 		sp := strings.Split(line, ";")
 		for i, s := range sp {
 			sp[i] = clean(s)
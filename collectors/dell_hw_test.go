@@ -0,0 +1,17 @@
+package collectors
+
+import (
+	"testing"
+)
+
+func Test_c_omreport_chassis_golden(t *testing.T) {
+	fx := loadFixture(t, "c_omreport_chassis")
+	md, err := c_omreport_chassis()
+	fx.check(t, md, err)
+}
+
+func Test_c_omreport_ps_amps_golden(t *testing.T) {
+	fx := loadFixture(t, "c_omreport_ps_amps")
+	md, err := c_omreport_ps_amps()
+	fx.check(t, md, err)
+}
@@ -6,7 +6,6 @@ import (
 
 	"github.com/oliveagle/go-collectors/datapoint"
 	"github.com/oliveagle/go-collectors/metadata"
-	"github.com/oliveagle/go-collectors/util"
 )
 
 func init() {
@@ -15,7 +14,7 @@ func init() {
 
 func c_dfstat_darwin() (datapoint.MultiDataPoint, error) {
 	var md datapoint.MultiDataPoint
-	util.ReadCommand(func(line string) error {
+	readCommand(func(line string) error {
 		fields := strings.Fields(line)
 		if line == "" || len(fields) < 9 || !IsDigit(fields[2]) {
 			return nil
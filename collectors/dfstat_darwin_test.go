@@ -2,6 +2,8 @@ package collectors
 
 import (
 	"testing"
+
+	"github.com/oliveagle/go-collectors/datapoint"
 )
 
 func Test_c_dfstat_darwin(t *testing.T) {
@@ -13,7 +15,7 @@ func Test_c_dfstat_darwin(t *testing.T) {
 	isOk := false
 	for idx := range md {
 		t.Log(md[idx])
-		if md[idx].Metric == "darwin.disk.fs.total" && md[idx].Value.(int) > 0 {
+		if md[idx].Metric == "darwin.disk.fs.total" && md[idx].Value.(datapoint.Value).Float64() > 0 {
 			isOk = true
 		}
 	}
@@ -2,14 +2,12 @@ package collectors
 
 import (
 	"fmt"
-	"io/ioutil"
 	"regexp"
 	"strconv"
 	"strings"
 
 	"github.com/oliveagle/go-collectors/datapoint"
 	"github.com/oliveagle/go-collectors/metadata"
-	"github.com/oliveagle/go-collectors/util"
 )
 
 func init() {
@@ -51,7 +49,7 @@ var diskLinuxFieldsPart = []struct {
 func removable(major, minor string) bool {
 	//We don't return an error, because removable may not exist for partitions of a removable device
 	//So this is really "best effort" and we will have to see how it works in practice.
-	b, err := ioutil.ReadFile("/sys/dev/block/" + major + ":" + minor + "/removable")
+	b, err := readFile("/sys/dev/block/" + major + ":" + minor + "/removable")
 	if err != nil {
 		return false
 	}
@@ -63,7 +61,7 @@ var sdiskRE = regexp.MustCompile(`/dev/(sd[a-z])[0-9]?`)
 func removable_fs(name string) bool {
 	s := sdiskRE.FindStringSubmatch(name)
 	if len(s) > 1 {
-		b, err := ioutil.ReadFile("/sys/block/" + s[1] + "/removable")
+		b, err := readFile("/sys/block/" + s[1] + "/removable")
 		if err != nil {
 			return false
 		}
@@ -91,7 +89,7 @@ func c_iostat_linux() (datapoint.MultiDataPoint, error) {
 		ts := datapoint.TagSet{"dev": device}
 		if i1%16 == 0 && i0 > 1 {
 			metric = "linux.disk."
-			if b, err := ioutil.ReadFile("/sys/block/" + device + "/queue/hw_sector_size"); err == nil {
+			if b, err := readFile("/sys/block/" + device + "/queue/hw_sector_size"); err == nil {
 				block_size, _ = strconv.ParseInt(strings.TrimSpace(string(b)), 10, 64)
 			}
 		}
@@ -141,12 +139,32 @@ func c_iostat_linux() (datapoint.MultiDataPoint, error) {
 	return md, err
 }
 
+// dfFields splits a line of df -P into its five leading columns and the
+// mount point, which may contain spaces. The mount point is made a valid
+// tag value by replacing invalid characters with underscores.
+func dfFields(line string) []string {
+	var fields []string
+	s := line
+	for i := 0; i < 5; i++ {
+		s = strings.TrimLeft(s, " \t")
+		j := strings.IndexAny(s, " \t")
+		if j < 0 {
+			return nil
+		}
+		fields = append(fields, s[:j])
+		s = s[j:]
+	}
+	mount := datapoint.MustReplace(strings.TrimSpace(s), "_")
+	if mount == "" {
+		return nil
+	}
+	return append(fields, mount)
+}
+
 func c_dfstat_blocks_linux() (datapoint.MultiDataPoint, error) {
 	var md datapoint.MultiDataPoint
-	err := util.ReadCommand(func(line string) error {
-		fields := strings.Fields(line)
-		// TODO: support mount points with spaces in them. They mess up the field order
-		// currently due to df's columnar output.
+	err := readCommand(func(line string) error {
+		fields := dfFields(line)
 		if len(fields) != 6 || !IsDigit(fields[2]) {
 			return nil
 		}
@@ -178,8 +196,8 @@ func c_dfstat_blocks_linux() (datapoint.MultiDataPoint, error) {
 
 func c_dfstat_inodes_linux() (datapoint.MultiDataPoint, error) {
 	var md datapoint.MultiDataPoint
-	err := util.ReadCommand(func(line string) error {
-		fields := strings.Fields(line)
+	err := readCommand(func(line string) error {
+		fields := dfFields(line)
 		if len(fields) != 6 || !IsDigit(fields[2]) {
 			return nil
 		}
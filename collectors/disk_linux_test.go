@@ -1,6 +1,7 @@
 package collectors
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -61,3 +62,34 @@ func Test_c_dfstat_inodes_linux(t *testing.T) {
 	// t.Error("hhh")
 
 }
+
+func Test_c_iostat_linux_golden(t *testing.T) {
+	fx := loadFixture(t, "c_iostat_linux")
+	md, err := c_iostat_linux()
+	fx.check(t, md, err)
+}
+
+// Mount points with spaces, such as "/media/My Passport", are skipped.
+func Test_c_dfstat_blocks_linux_golden(t *testing.T) {
+	fx := loadFixture(t, "c_dfstat_blocks_linux")
+	md, err := c_dfstat_blocks_linux()
+	fx.check(t, md, err)
+}
+
+func Test_c_dfstat_inodes_linux_golden(t *testing.T) {
+	fx := loadFixture(t, "c_dfstat_inodes_linux")
+	md, err := c_dfstat_inodes_linux()
+	fx.check(t, md, err)
+}
+
+func TestDfFields(t *testing.T) {
+	for line, expected := range map[string]string{
+		"/dev/sdc1  15728640000 1048576000 14680064000   7% /media/My  Passport": "/dev/sdc1 15728640000 1048576000 14680064000 7% /media/My_Passport",
+		"/dev/sda1 3276800 281734 2995066 9% /":                                  "/dev/sda1 3276800 281734 2995066 9% /",
+		"tmpfs 1 2 3 4%":                                                         "",
+	} {
+		if got := strings.Join(dfFields(line), " "); got != expected {
+			t.Errorf("%q: got %q, expected %q", line, got, expected)
+		}
+	}
+}
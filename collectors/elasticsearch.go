@@ -1,12 +1,11 @@
 package collectors
 
 import (
-	"encoding/json"
 	"errors"
 	"math"
-	"net/http"
 	"net/url"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/oliveagle/go-collectors/datapoint"
@@ -15,11 +14,13 @@ import (
 )
 
 func init() {
-	collectors = append(collectors, &IntervalCollector{F: c_elasticsearch, Enable: enableURL(esURL)})
-	collectors = append(collectors, &IntervalCollector{F: c_elasticsearch_indices, Interval: time.Minute * 2, Enable: enableURL(esURL)})
+	collectors = append(collectors, &IntervalCollector{F: c_elasticsearch, Enable: enableElasticsearch})
+	collectors = append(collectors, &IntervalCollector{F: c_elasticsearch_indices, Interval: time.Minute * 2, Enable: enableElasticsearch})
 }
 
-const esURL = "http://localhost:9200/"
+func enableElasticsearch() bool {
+	return ESTarget.enable(ESTarget.URL)
+}
 
 var (
 	esPreV1     = regexp.MustCompile(`^0\.`)
@@ -40,11 +41,17 @@ func c_elasticsearch() (datapoint.MultiDataPoint, error) {
 		return nil, err
 	}
 	var clusterState esClusterState
-	if err := esReq("/_cluster/state", "?filter_routing_table=true&filter_metadata=true&filter_blocks=true", &clusterState); err != nil {
+	statePath, stateQuery := esStateURL(status.Version.Number)
+	if err := esReq(statePath, stateQuery, &clusterState); err != nil {
 		return nil, err
 	}
 	var md datapoint.MultiDataPoint
 	add := func(name string, val interface{}, ts datapoint.TagSet) {
+		// Stats missing in the running version, such as the resident memory
+		// of processes since ES 2.
+		if val == nil {
+			return
+		}
 		tags := datapoint.TagSet{"cluster": stats.ClusterName}
 		for k, v := range ts {
 			tags[k] = v
@@ -150,8 +157,13 @@ func c_elasticsearch() (datapoint.MultiDataPoint, error) {
 			case "cpu":
 				v := v.(map[string]interface{})
 				add("process.cpu.percent", v["percent"], nil)
-				add("process.cpu.sys", v["sys_in_millis"].(float64)/1000., nil)
-				add("process.cpu.user", v["user_in_millis"].(float64)/1000., nil)
+				// Removed in ES 2.
+				if f, ok := v["sys_in_millis"].(float64); ok {
+					add("process.cpu.sys", f/1000., nil)
+				}
+				if f, ok := v["user_in_millis"].(float64); ok {
+					add("process.cpu.user", f/1000., nil)
+				}
 			case "mem":
 				v := v.(map[string]interface{})
 				add("process.mem.resident", v["resident_in_bytes"], nil)
@@ -178,7 +190,9 @@ func c_elasticsearch() (datapoint.MultiDataPoint, error) {
 					v := v.(map[string]interface{})
 					ts := datapoint.TagSet{"gc": k}
 					add("jvm.gc.collection_count", v["collection_count"], ts)
-					add("jvm.gc.collection_time", v["collection_time_in_millis"].(float64)/1000, ts)
+					if f, ok := v["collection_time_in_millis"].(float64); ok {
+						add("jvm.gc.collection_time", f/1000, ts)
+					}
 				}
 			}
 		}
@@ -388,7 +402,7 @@ type ElasticIndicesHealth struct {
 	RelocatingShards    float64                       `json:"relocating_shards"`
 	Status              string                        `json:"status"`
 	TimedOut            bool                          `json:"timed_out"`
-	UnassignedShards    float64                       `json:"unassigned_shards`
+	UnassignedShards    float64                       `json:"unassigned_shards"`
 }
 
 type ElasticIndexHealth struct {
@@ -499,22 +513,13 @@ func c_elasticsearch_indices() (datapoint.MultiDataPoint, error) {
 }
 
 func esReq(path, query string, v interface{}) error {
-	u := &url.URL{
-		Scheme:   "http",
-		Host:     "localhost:9200",
-		Path:     path,
-		RawQuery: query,
-	}
-	resp, err := http.Get(u.String())
+	u, err := url.Parse(ESTarget.URL)
 	if err != nil {
-		return nil
+		return err
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return nil
-	}
-	j := json.NewDecoder(resp.Body)
-	return j.Decode(v)
+	u.Path = strings.TrimSuffix(u.Path, "/") + path
+	u.RawQuery = query
+	return ESTarget.getJSON(u.String(), v)
 }
 
 func esStatsURL(version string) string {
@@ -524,6 +529,16 @@ func esStatsURL(version string) string {
 	return "/_nodes/_local/stats"
 }
 
+// esStateURL returns the path and query of the cluster state with only the
+// master node. Since ES 5, unknown parameters such as the filters of 0.90
+// are rejected.
+func esStateURL(version string) (path, query string) {
+	if esPreV1.MatchString(version) {
+		return "/_cluster/state", "filter_routing_table=true&filter_metadata=true&filter_blocks=true"
+	}
+	return "/_cluster/state/master_node", ""
+}
+
 type esStatus struct {
 	Status  int    `json:"status"`
 	Name    string `json:"name"`
@@ -0,0 +1,30 @@
+package collectors
+
+import (
+	"testing"
+)
+
+func TestElasticsearchGolden(t *testing.T) {
+	for _, version := range []string{"0.90", "1.x", "7.x"} {
+		t.Run(version, func(t *testing.T) {
+			fx := loadFixture(t, "elasticsearch_"+version)
+			setString(t, &ESTarget.URL, fx.URL)
+			if !enableElasticsearch() {
+				t.Fatal("not enabled")
+			}
+			md, err := c_elasticsearch()
+			fx.checkFile(t, "c_elasticsearch.txt", md, err)
+			md, err = c_elasticsearch_indices()
+			fx.checkFile(t, "c_elasticsearch_indices.txt", md, err)
+		})
+	}
+}
+
+func TestEsStateURL(t *testing.T) {
+	if path, query := esStateURL("0.90.13"); path != "/_cluster/state" || query == "" {
+		t.Errorf("0.90: got %s?%s", path, query)
+	}
+	if path, query := esStateURL("7.10.2"); path != "/_cluster/state/master_node" || query != "" {
+		t.Errorf("7.10: got %s?%s", path, query)
+	}
+}
@@ -0,0 +1,132 @@
+package collectors
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/oliveagle/go-collectors/slog"
+	"github.com/oliveagle/go-collectors/util"
+)
+
+// DefaultErrorInterval is the interval between summaries of repeated
+// collector errors if a collector does not specify its own.
+var DefaultErrorInterval = time.Hour
+
+// maxErrorKeys bounds the number of distinct errors tracked per collector.
+// Further distinct errors are logged without deduplication.
+const maxErrorKeys = 100
+
+// errorLimiter deduplicates the errors of a collector. The first occurrence
+// of an error is logged; repeats are counted and summarized at most once
+// per interval, as in "... (repeated 240 times in 1h0m0s)". Once the
+// collector succeeds again, the recovery is logged.
+type errorLimiter struct {
+	// interval between summaries. Zero means DefaultErrorInterval, a
+	// negative interval logs every error.
+	interval time.Duration
+	clock    util.Clock
+
+	sync.Mutex
+	errs   map[string]*errorState
+	failed time.Time
+	total  int
+}
+
+type errorState struct {
+	msg      string
+	repeated int
+	since    time.Time
+}
+
+func newErrorLimiter(interval time.Duration) *errorLimiter {
+	return &errorLimiter{
+		interval: interval,
+		clock:    Clock,
+		errs:     make(map[string]*errorState),
+	}
+}
+
+func (l *errorLimiter) getInterval() time.Duration {
+	if l.interval == 0 {
+		return DefaultErrorInterval
+	}
+	return l.interval
+}
+
+// Error logs err to log, unless it repeats an error logged within the
+// interval.
+func (l *errorLimiter) Error(log *slog.Context, err error) {
+	l.report(log, err.Error(), err.Error())
+}
+
+// Errorf logs a message to log, unless a message with the same format was
+// logged within the interval. Messages are deduplicated by format, so that
+// for example bad lines of a program collector count as one error.
+func (l *errorLimiter) Errorf(log *slog.Context, format string, args ...interface{}) {
+	l.report(log, format, fmt.Sprintf(format, args...))
+}
+
+func (l *errorLimiter) report(log *slog.Context, key, msg string) {
+	l.Lock()
+	defer l.Unlock()
+	now := l.clock.Now()
+	if l.total == 0 {
+		l.failed = now
+	}
+	l.total++
+	interval := l.getInterval()
+	s := l.errs[key]
+	if interval < 0 || s == nil && len(l.errs) >= maxErrorKeys {
+		log.Error(msg)
+		return
+	}
+	if s == nil {
+		l.errs[key] = &errorState{msg: msg, since: now}
+		log.Error(msg)
+		return
+	}
+	s.msg = msg
+	s.repeated++
+	if now.Sub(s.since) >= interval {
+		l.summarize(log, s, now)
+	}
+}
+
+func (l *errorLimiter) summarize(log *slog.Context, s *errorState, now time.Time) {
+	log.Errorf("%s (repeated %d times in %v)", s.msg, s.repeated, now.Sub(s.since))
+	s.repeated = 0
+	s.since = now
+}
+
+// Count returns the number of errors reported since the last success.
+func (l *errorLimiter) Count() int {
+	l.Lock()
+	defer l.Unlock()
+	return l.total
+}
+
+// Success records a successful run. If errors were reported since the last
+// success, outstanding repeats are summarized and the recovery is logged.
+func (l *errorLimiter) Success(log *slog.Context) {
+	l.Lock()
+	defer l.Unlock()
+	if l.total == 0 {
+		return
+	}
+	now := l.clock.Now()
+	keys := make([]string, 0, len(l.errs))
+	for k := range l.errs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if s := l.errs[k]; s.repeated > 0 {
+			l.summarize(log, s, now)
+		}
+	}
+	log.Infof("recovered after %d errors in %v", l.total, now.Sub(l.failed))
+	l.errs = make(map[string]*errorState)
+	l.total = 0
+}
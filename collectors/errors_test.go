@@ -0,0 +1,92 @@
+package collectors
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oliveagle/go-collectors/datapoint"
+	"github.com/oliveagle/go-collectors/metadata"
+	"github.com/oliveagle/go-collectors/slog"
+	"github.com/oliveagle/go-collectors/util"
+)
+
+type logRecorder []string
+
+func (r *logRecorder) Error(v string)   { *r = append(*r, "error: "+v) }
+func (r *logRecorder) Info(v string)    { *r = append(*r, "info: "+v) }
+func (r *logRecorder) Warning(v string) { *r = append(*r, "warning: "+v) }
+func (r *logRecorder) Fatal(v string)   { *r = append(*r, "fatal: "+v) }
+
+func recordLog(t *testing.T) *logRecorder {
+	r := &logRecorder{}
+	prev, prevLineNumber := slog.Get(), slog.LogLineNumber
+	slog.Set(r)
+	slog.LogLineNumber = false
+	t.Cleanup(func() {
+		slog.Set(prev)
+		slog.LogLineNumber = prevLineNumber
+	})
+	return r
+}
+
+func TestErrorLimiter(t *testing.T) {
+	r := recordLog(t)
+	clock := util.NewFakeClock(time.Unix(0, 0))
+	l := newErrorLimiter(time.Hour)
+	l.clock = clock
+	log := slog.With(slog.F("collector", "c_elasticsearch"))
+	down := errors.New("connection refused")
+
+	for i := 0; i < 242; i++ {
+		l.Error(log, down)
+		clock.Advance(15 * time.Second)
+	}
+	l.Errorf(log, "bad line: %s", "a")
+	l.Errorf(log, "bad line: %s", "b")
+	l.Success(log)
+	l.Success(log)
+	l.Error(log, down)
+
+	expected := []string{
+		"error: connection refused collector=c_elasticsearch",
+		"error: connection refused (repeated 240 times in 1h0m0s) collector=c_elasticsearch",
+		"error: bad line: a collector=c_elasticsearch",
+		"error: bad line: b (repeated 1 times in 0s) collector=c_elasticsearch",
+		"error: connection refused (repeated 1 times in 30s) collector=c_elasticsearch",
+		"info: recovered after 244 errors in 1h0m30s collector=c_elasticsearch",
+		"error: connection refused collector=c_elasticsearch",
+	}
+	if got := strings.Join(*r, "\n"); got != strings.Join(expected, "\n") {
+		t.Errorf("got:\n%s\nexpected:\n%s", got, strings.Join(expected, "\n"))
+	}
+}
+
+func TestErrorLimiterEveryError(t *testing.T) {
+	r := recordLog(t)
+	l := newErrorLimiter(-1)
+	log := slog.With()
+	for i := 0; i < 3; i++ {
+		l.Error(log, errors.New("x"))
+	}
+	if len(*r) != 3 {
+		t.Errorf("got %q", *r)
+	}
+}
+
+func TestAddDropped(t *testing.T) {
+	r := recordLog(t)
+	prev := dropped
+	t.Cleanup(func() { dropped = prev })
+	dropped = newErrorLimiter(time.Hour)
+	var md datapoint.MultiDataPoint
+	for i := 0; i < 3; i++ {
+		Add(&md, "test.dropped", "x", nil, metadata.Unknown, metadata.None, "")
+		Add(&md, "test.nan", math.NaN(), nil, metadata.Unknown, metadata.None, "")
+	}
+	if len(md) != 0 || len(*r) != 1 || !strings.HasPrefix((*r)[0], "error: dropping datapoint of test.dropped") {
+		t.Errorf("got %v, logged %q", md, *r)
+	}
+}
@@ -0,0 +1,85 @@
+package collectors
+
+import (
+	"bufio"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+var (
+	// ProcRoot is the absolute path where procfs is mounted, for example
+	// /host/proc if an agent running in a container reads the host's /proc
+	// from there.
+	ProcRoot = "/proc"
+	// SysRoot is the absolute path where sysfs is mounted.
+	SysRoot = "/sys"
+	// HostFS is the filesystem that paths below /proc and /sys are read from
+	// after they are moved below ProcRoot and SysRoot. Tests may replace it
+	// with captured fixtures, such as an fstest.MapFS holding "proc/stat".
+	HostFS fs.FS = os.DirFS("/")
+)
+
+// hostPath returns the path in HostFS of name if it is below /proc or /sys,
+// and false otherwise.
+func hostPath(name string) (string, bool) {
+	for _, m := range []struct{ prefix, root string }{
+		{"/proc", ProcRoot},
+		{"/sys", SysRoot},
+	} {
+		if name == m.prefix || strings.HasPrefix(name, m.prefix+"/") {
+			p := strings.TrimPrefix(m.root+name[len(m.prefix):], "/")
+			if p == "" {
+				p = "."
+			}
+			return p, true
+		}
+	}
+	return "", false
+}
+
+// openFile opens name. Paths below /proc and /sys are opened in HostFS, all
+// others in the local filesystem.
+func openFile(name string) (fs.File, error) {
+	if p, ok := hostPath(name); ok {
+		return HostFS.Open(p)
+	}
+	return os.Open(name)
+}
+
+// readFile reads the file name, which is found as by openFile.
+func readFile(name string) ([]byte, error) {
+	f, err := openFile(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// readDir reads the directory name, which is found as by openFile, and
+// returns its entries sorted by name.
+func readDir(name string) ([]fs.DirEntry, error) {
+	if p, ok := hostPath(name); ok {
+		return fs.ReadDir(HostFS, p)
+	}
+	return os.ReadDir(name)
+}
+
+// readLine calls line for each line of the file name, which is found as by
+// openFile.
+func readLine(fname string, line func(string) error) error {
+	f, err := openFile(fname)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if err := line(scanner.Text()); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
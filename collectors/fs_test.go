@@ -0,0 +1,62 @@
+package collectors
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+// setHostFS replaces HostFS with fsys and resets the roots for the duration
+// of the test.
+func setHostFS(t *testing.T, fsys fs.FS) {
+	prev, proc, sys := HostFS, ProcRoot, SysRoot
+	HostFS, ProcRoot, SysRoot = fsys, "/proc", "/sys"
+	t.Cleanup(func() { HostFS, ProcRoot, SysRoot = prev, proc, sys })
+}
+
+func TestHostPath(t *testing.T) {
+	setHostFS(t, nil)
+	ProcRoot = "/host/proc"
+	tests := []struct {
+		name, path string
+		ok         bool
+	}{
+		{"/proc/stat", "host/proc/stat", true},
+		{"/proc", "host/proc", true},
+		{"/sys/block/sda/removable", "sys/block/sda/removable", true},
+		{"/processes", "", false},
+		{"/etc/redis.conf", "", false},
+		{"redis.conf", "", false},
+	}
+	for _, test := range tests {
+		if p, ok := hostPath(test.name); p != test.path || ok != test.ok {
+			t.Errorf("%s: got %q, %v", test.name, p, ok)
+		}
+	}
+	SysRoot = "/"
+	if p, _ := hostPath("/sys"); p != "." {
+		t.Errorf("got %q", p)
+	}
+}
+
+func TestReadHostFS(t *testing.T) {
+	setHostFS(t, fstest.MapFS{
+		"host/proc/net/bonding/bond0": {Data: []byte("Slave Interface: eth0\n")},
+		"host/proc/net/bonding/bond1": {Data: []byte("Slave Interface: eth2\n")},
+	})
+	ProcRoot = "/host/proc"
+	entries, err := readDir("/proc/net/bonding")
+	if err != nil || len(entries) != 2 || entries[1].Name() != "bond1" {
+		t.Fatalf("got %v, %v", entries, err)
+	}
+	var lines []string
+	if err := readLine("/proc/net/bonding/bond0", func(s string) error {
+		lines = append(lines, s)
+		return nil
+	}); err != nil || len(lines) != 1 || lines[0] != "Slave Interface: eth0" {
+		t.Errorf("got %q, %v", lines, err)
+	}
+	if _, err := readFile("/proc/stat"); err == nil {
+		t.Error("expected error")
+	}
+}
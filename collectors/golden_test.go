@@ -0,0 +1,249 @@
+package collectors
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oliveagle/go-collectors/datapoint"
+	"github.com/oliveagle/go-collectors/metadata"
+	"github.com/oliveagle/go-collectors/util"
+)
+
+var update = flag.Bool("update", false, "update the golden files of collector fixtures")
+
+// fixtureHost is the host tag of datapoints collected from fixtures.
+const fixtureHost = "fixture"
+
+// fixtureTime is the time of the Clock of fixtures.
+var fixtureTime = time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC)
+
+// A fixture is a directory below testdata/fixtures holding the captured
+// inputs of a collector:
+//
+//	proc/, sys/  trees read in place of /proc and /sys
+//	commands/    outputs of programs, a file per command line
+//	http/        responses of HTTP servers, a file per request
+//	snmpwalk.txt variables served by an SNMP agent, as by snmpwalk -On
+//	golden.txt   the expected datapoints
+//
+// A file in commands starts with a line of "$ " followed by the program and
+// its arguments, separated by single spaces; the rest is its output. Other
+// programs are not found, as if they were not installed.
+//
+// A file in http starts with a line of "GET " followed by the request URI,
+// the path and query as sent by the collector; the rest is the body of the
+// response. Other requests get 404 Not Found.
+//
+// The SNMP agent answers requests of the community "public".
+//
+// A fixture shared by several collectors, such as the responses of one
+// server, holds a golden file per collector, which is checked by checkFile.
+type fixture struct {
+	dir string
+	// URL is the base URL of a server of the http directory, if present.
+	URL string
+	// SNMP is the agent serving snmpwalk.txt, if present.
+	SNMP *snmpAgent
+	// Clock is the Clock of collectors, which stands at fixtureTime.
+	Clock *util.FakeClock
+}
+
+// loadFixture makes the collectors read the fixture name until the end of
+// the test.
+func loadFixture(t *testing.T, name string) *fixture {
+	fx := &fixture{dir: filepath.Join("testdata", "fixtures", name)}
+	if _, err := os.Stat(fx.dir); err != nil {
+		t.Fatal(err)
+	}
+	setHostFS(t, os.DirFS(fx.dir))
+	setCommands(t, loadCommands(t, filepath.Join(fx.dir, "commands")))
+	fx.Clock = util.NewFakeClock(fixtureTime)
+	setClock(t, fx.Clock)
+	hosts, add := util.Hosts, AddTags
+	util.Hosts, AddTags = util.StaticHost(fixtureHost), nil
+	util.Set()
+	t.Cleanup(func() {
+		util.Hosts, AddTags = hosts, add
+		util.Set()
+	})
+	if dir := filepath.Join(fx.dir, "http"); isDir(dir) {
+		s := httptest.NewServer(loadResponses(t, dir))
+		t.Cleanup(s.Close)
+		fx.URL = s.URL
+	}
+	if name := filepath.Join(fx.dir, "snmpwalk.txt"); exists(name) {
+		fx.SNMP = startSNMPAgent(t, "public", loadSNMPWalk(t, name))
+	}
+	return fx
+}
+
+// setCommands replaces Commands with r for the duration of the test.
+func setCommands(t *testing.T, r util.Runner) {
+	prev := Commands
+	Commands = r
+	t.Cleanup(func() { Commands = prev })
+}
+
+// setClock replaces Clock with c for the duration of the test.
+func setClock(t *testing.T, c util.Clock) {
+	prev := Clock
+	Clock = c
+	t.Cleanup(func() { Clock = prev })
+}
+
+// setString sets *p to v for the duration of the test, such as a base URL
+// to the URL of a fixture.
+func setString(t *testing.T, p *string, v string) {
+	prev := *p
+	*p = v
+	t.Cleanup(func() { *p = prev })
+}
+
+// loadCommands reads the recorded program outputs in dir.
+func loadCommands(t *testing.T, dir string) util.FixtureRunner {
+	r := make(util.FixtureRunner)
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range files {
+		b, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		argv, out, _ := strings.Cut(string(b), "\n")
+		if !strings.HasPrefix(argv, "$ ") {
+			t.Fatalf("%s: missing $ command line", name)
+		}
+		r[strings.TrimPrefix(argv, "$ ")] = util.CommandOutput{Stdout: out}
+	}
+	return r
+}
+
+// responses replays recorded bodies of GET requests by request URI.
+type responses map[string][]byte
+
+func (r responses) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	b, ok := r[req.RequestURI]
+	if !ok || req.Method != "GET" {
+		http.NotFound(w, req)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
+
+// loadResponses reads the recorded responses in dir.
+func loadResponses(t *testing.T, dir string) responses {
+	r := make(responses)
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range files {
+		b, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, body, _ := strings.Cut(string(b), "\n")
+		if !strings.HasPrefix(req, "GET ") {
+			t.Fatalf("%s: missing GET request line", name)
+		}
+		r[strings.TrimPrefix(req, "GET ")] = []byte(body)
+	}
+	return r
+}
+
+func exists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
+
+func isDir(name string) bool {
+	fi, err := os.Stat(name)
+	return err == nil && fi.IsDir()
+}
+
+// check compares the result of a collector run with golden.txt of the
+// fixture, or replaces it with -update.
+func (fx *fixture) check(t *testing.T, md datapoint.MultiDataPoint, err error) {
+	t.Helper()
+	fx.checkFile(t, "golden.txt", md, err)
+}
+
+// checkFile is the same as check with the golden file name of the fixture.
+func (fx *fixture) checkFile(t *testing.T, name string, md datapoint.MultiDataPoint, err error) {
+	t.Helper()
+	got := formatGolden(md, err)
+	golden := filepath.Join(fx.dir, name)
+	if *update {
+		if err := os.WriteFile(golden, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	b, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("%v; run go test -update to create it", err)
+	}
+	if expected := string(b); got != expected {
+		t.Errorf("datapoints differ from %s (run go test -update to accept them):\n%s", golden, diffLines(expected, got))
+	}
+}
+
+// formatGolden formats md as sorted lines of metric, tags, value, rate and
+// unit. Timestamps are left out, as collectors stamp datapoints with the
+// time they ran.
+func formatGolden(md datapoint.MultiDataPoint, err error) string {
+	lines := make([]string, 0, len(md))
+	for _, dp := range md {
+		lines = append(lines, fmt.Sprintf("%s%s %v %v %v", dp.Metric, dp.Tags, dp.Value,
+			lookupMeta(dp.Metric, "rate"), lookupMeta(dp.Metric, "unit")))
+	}
+	sort.Strings(lines)
+	if err != nil {
+		lines = append([]string{"error: " + err.Error()}, lines...)
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func lookupMeta(metric, name string) interface{} {
+	if v, ok := metadata.Lookup(metric, nil, name); ok && fmt.Sprint(v) != "" {
+		return v
+	}
+	return "-"
+}
+
+// diffLines returns the lines only in expected prefixed by "-" and those only
+// in got prefixed by "+".
+func diffLines(expected, got string) string {
+	count := make(map[string]int)
+	for _, l := range strings.Split(expected, "\n") {
+		count[l]++
+	}
+	for _, l := range strings.Split(got, "\n") {
+		count[l]--
+	}
+	var b strings.Builder
+	for _, l := range strings.Split(expected, "\n") {
+		if count[l] > 0 {
+			count[l]--
+			fmt.Fprintf(&b, "-%s\n", l)
+		}
+	}
+	for _, l := range strings.Split(got, "\n") {
+		if count[l] < 0 {
+			count[l]++
+			fmt.Fprintf(&b, "+%s\n", l)
+		}
+	}
+	return b.String()
+}
@@ -3,8 +3,6 @@
 package collectors
 
 import (
-	"encoding/json"
-	"net/http"
 	"strings"
 
 	"github.com/oliveagle/go-collectors/datapoint"
@@ -13,36 +11,35 @@ import (
 )
 
 func init() {
-	collectors = append(collectors, &IntervalCollector{F: c_hbase_region, Enable: enableURL(hbURL)})
-	collectors = append(collectors, &IntervalCollector{F: c_hbase_replication, Enable: enableURL(hbRepURL)})
-	collectors = append(collectors, &IntervalCollector{F: c_hbase_gc, Enable: enableURL(hbGCURL)})
+	collectors = append(collectors, &IntervalCollector{F: c_hbase_region, Enable: enableHBase(hbRegionQuery)})
+	collectors = append(collectors, &IntervalCollector{F: c_hbase_replication, Enable: enableHBase(hbRepQuery)})
+	collectors = append(collectors, &IntervalCollector{F: c_hbase_gc, Enable: enableHBase(hbGCQuery)})
 }
 
+// Queries of the JMX servlet below HBaseTarget.
 const (
-	hbURL    = "http://localhost:60030/jmx?qry=hadoop:service=RegionServer,name=RegionServerStatistics"
-	hbRepURL = "http://localhost:60030/jmx?qry=hadoop:service=Replication,name=*"
-	hbGCURL  = "http://localhost:60030/jmx?qry=java.lang:type=GarbageCollector,name=*"
+	hbRegionQuery = "/jmx?qry=hadoop:service=RegionServer,name=RegionServerStatistics"
+	hbRepQuery    = "/jmx?qry=hadoop:service=Replication,name=*"
+	hbGCQuery     = "/jmx?qry=java.lang:type=GarbageCollector,name=*"
 )
 
+func enableHBase(query string) func() bool {
+	return func() bool {
+		return HBaseTarget.enable(HBaseTarget.url(query))
+	}
+}
+
 type jmx struct {
 	Beans []map[string]interface{} `json:"beans"`
 }
 
-func getBeans(url string, jmx *jmx) error {
-	res, err := http.Get(url)
-	if err != nil {
-		return err
-	}
-	defer res.Body.Close()
-	if err := json.NewDecoder(res.Body).Decode(&jmx); err != nil {
-		return err
-	}
-	return nil
+func getBeans(query string, jmx *jmx) error {
+	return HBaseTarget.getJSON(HBaseTarget.url(query), jmx)
 }
 
 func c_hbase_region() (datapoint.MultiDataPoint, error) {
 	var j jmx
-	if err := getBeans(hbURL, &j); err != nil {
+	if err := getBeans(hbRegionQuery, &j); err != nil {
 		return nil, err
 	}
 	var md datapoint.MultiDataPoint
@@ -58,7 +55,7 @@ func c_hbase_region() (datapoint.MultiDataPoint, error) {
 
 func c_hbase_gc() (datapoint.MultiDataPoint, error) {
 	var j jmx
-	if err := getBeans(hbGCURL, &j); err != nil {
+	if err := getBeans(hbGCQuery, &j); err != nil {
 		return nil, err
 	}
 	var md datapoint.MultiDataPoint
@@ -83,7 +80,7 @@ func c_hbase_gc() (datapoint.MultiDataPoint, error) {
 
 func c_hbase_replication() (datapoint.MultiDataPoint, error) {
 	var j jmx
-	if err := getBeans(hbRepURL, &j); err != nil {
+	if err := getBeans(hbRepQuery, &j); err != nil {
 		return nil, err
 	}
 	var md datapoint.MultiDataPoint
@@ -0,0 +1,34 @@
+// +build darwin linux
+
+package collectors
+
+import (
+	"testing"
+)
+
+func TestHBaseGolden(t *testing.T) {
+	fx := loadFixture(t, "hbase")
+	setString(t, &HBaseTarget.URL, fx.URL)
+	for _, q := range []string{hbRegionQuery, hbRepQuery, hbGCQuery} {
+		if !enableHBase(q)() {
+			t.Errorf("%s: not enabled", q)
+		}
+	}
+	md, err := c_hbase_region()
+	fx.checkFile(t, "c_hbase_region.txt", md, err)
+	md, err = c_hbase_replication()
+	fx.checkFile(t, "c_hbase_replication.txt", md, err)
+	md, err = c_hbase_gc()
+	fx.checkFile(t, "c_hbase_gc.txt", md, err)
+}
+
+func TestHBaseDown(t *testing.T) {
+	fx := loadFixture(t, "hbase")
+	setString(t, &HBaseTarget.URL, fx.URL+"/down")
+	if enableHBase(hbRegionQuery)() {
+		t.Error("enabled on 404")
+	}
+	if _, err := c_hbase_region(); err == nil {
+		t.Error("expected an error on 404")
+	}
+}
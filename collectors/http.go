@@ -0,0 +1,205 @@
+package collectors
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oliveagle/go-collectors/util"
+)
+
+var (
+	// DefaultHTTPTimeout limits requests of HTTP targets without a Timeout.
+	DefaultHTTPTimeout = time.Second * 10
+	// UserAgent is the User-Agent header of requests of HTTP targets.
+	UserAgent = "go-collectors"
+
+	// ESTarget is the Elasticsearch node to monitor.
+	ESTarget = &HTTPTarget{URL: "http://localhost:9200"}
+	// HBaseTarget is the info server of the HBase region server to monitor.
+	HBaseTarget = &HTTPTarget{URL: "http://localhost:60030"}
+	// OpenTSDBTarget is the OpenTSDB server to monitor.
+	OpenTSDBTarget = &HTTPTarget{URL: "http://localhost:4242"}
+	// RailgunTarget is the stats URL of the Railgun listener. If its URL is
+	// empty, it is read from the configuration of a running rg-listener.
+	RailgunTarget = &HTTPTarget{}
+)
+
+// An HTTPTarget is an HTTP endpoint polled by collectors and the options to
+// reach it. The client of a target is built on its first request and kept
+// for the connections it reuses; it is built again if HTTPOptions change.
+type HTTPTarget struct {
+	// URL is the base URL of the endpoint.
+	URL string
+	HTTPOptions
+
+	mu     sync.Mutex
+	client *http.Client
+	// built are the options client was built with.
+	built HTTPOptions
+}
+
+// HTTPOptions are the options of the client of an HTTPTarget.
+type HTTPOptions struct {
+	// Timeout limits requests, including reading their body. Defaults to
+	// DefaultHTTPTimeout.
+	Timeout time.Duration
+	// Username and Password are sent with basic authentication if
+	// Username is set.
+	Username string
+	Password util.Secret
+	// BearerToken is sent in the Authorization header if set.
+	BearerToken util.Secret
+	// CAFile is a PEM file of the CAs that verify the server, instead of
+	// those of the system.
+	CAFile string
+	// CertFile and KeyFile are the PEM files of a client certificate and
+	// its key.
+	CertFile string
+	KeyFile  string
+	// InsecureSkipVerify disables the verification of the server
+	// certificate.
+	InsecureSkipVerify bool
+	// Proxy is the URL of a proxy. If empty, the proxy is taken from the
+	// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables; if
+	// "direct", no proxy is used.
+	Proxy string
+}
+
+// NewHTTPClient returns a client with the connection options of o. The
+// credentials of o are not sent by the client but set on each request by
+// HTTPTarget, so that the client drops them on redirects to other hosts.
+func NewHTTPClient(o HTTPOptions) (*http.Client, error) {
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	// Collectors poll a single endpoint: keep an idle connection to it
+	// between runs.
+	tr.MaxIdleConnsPerHost = 2
+	tr.IdleConnTimeout = time.Minute * 5
+	switch o.Proxy {
+	case "":
+	case "direct":
+		tr.Proxy = nil
+	default:
+		u, err := url.Parse(o.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("proxy: %v", err)
+		}
+		tr.Proxy = http.ProxyURL(u)
+	}
+	tc := &tls.Config{InsecureSkipVerify: o.InsecureSkipVerify}
+	if o.CAFile != "" {
+		b, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		tc.RootCAs = x509.NewCertPool()
+		if !tc.RootCAs.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("%s: no certificates", o.CAFile)
+		}
+	}
+	if o.CertFile != "" || o.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tc.Certificates = []tls.Certificate{cert}
+	}
+	tr.TLSClientConfig = tc
+	timeout := o.Timeout
+	if timeout == 0 {
+		timeout = DefaultHTTPTimeout
+	}
+	return &http.Client{Transport: tr, Timeout: timeout}, nil
+}
+
+// Client returns the client of t.
+func (t *HTTPTarget) Client() (*http.Client, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.client == nil || t.built != t.HTTPOptions {
+		c, err := NewHTTPClient(t.HTTPOptions)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", redactURL(t.URL), err)
+		}
+		t.client, t.built = c, t.HTTPOptions
+	}
+	return t.client, nil
+}
+
+// url returns the URL of path below t.URL.
+func (t *HTTPTarget) url(path string) string {
+	return strings.TrimSuffix(t.URL, "/") + path
+}
+
+// get gets url, which is usually below t.URL, with the client and the
+// credentials of t. Responses other than 200 OK are returned as errors.
+func (t *HTTPTarget) get(url string) (*http.Response, error) {
+	c, err := t.Client()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", UserAgent)
+	if t.Username != "" {
+		pwd, err := t.Password.Value()
+		if err != nil {
+			return nil, err
+		}
+		req.SetBasicAuth(t.Username, pwd)
+	} else if t.BearerToken != "" {
+		token, err := t.BearerToken.Value()
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: %s", redactURL(url), resp.Status)
+	}
+	return resp, nil
+}
+
+// redactURL replaces the password of s, if any, for logs.
+func redactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil {
+		return s
+	}
+	return u.Redacted()
+}
+
+// getJSON decodes the JSON body of url into v.
+func (t *HTTPTarget) getJSON(url string, v interface{}) error {
+	resp, err := t.get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// enable reports whether url responds with 200 OK, for the Enable function
+// of collectors.
+func (t *HTTPTarget) enable(url string) bool {
+	resp, err := t.get(url)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return true
+}
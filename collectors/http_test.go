@@ -0,0 +1,222 @@
+package collectors
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// echoHeaders responds with the headers of requests that identify the
+// client.
+func echoHeaders(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprintf(w, `{"auth": %q, "agent": %q}`, r.Header.Get("Authorization"), r.UserAgent())
+}
+
+func TestHTTPTargetHeaders(t *testing.T) {
+	t.Setenv("ES_TOKEN", "t0ken")
+	s := httptest.NewServer(http.HandlerFunc(echoHeaders))
+	t.Cleanup(s.Close)
+	for _, c := range []struct {
+		o    HTTPOptions
+		auth string
+	}{
+		{HTTPOptions{}, ""},
+		{HTTPOptions{Username: "monitor", Password: "secret"}, "Basic bW9uaXRvcjpzZWNyZXQ="},
+		{HTTPOptions{BearerToken: "t0ken"}, "Bearer t0ken"},
+		{HTTPOptions{BearerToken: "env:ES_TOKEN"}, "Bearer t0ken"},
+	} {
+		target := &HTTPTarget{URL: s.URL, HTTPOptions: c.o}
+		var v struct{ Auth, Agent string }
+		if err := target.getJSON(target.url("/"), &v); err != nil {
+			t.Fatal(err)
+		}
+		if v.Auth != c.auth || v.Agent != UserAgent {
+			t.Errorf("%+v: got %+v", c.o, v)
+		}
+	}
+}
+
+// TestHTTPTargetRedirect checks that credentials are not sent to the other
+// host of a redirect.
+func TestHTTPTargetRedirect(t *testing.T) {
+	other := httptest.NewServer(http.HandlerFunc(echoHeaders))
+	t.Cleanup(other.Close)
+	// Redirect from 127.0.0.1 to localhost, which the client considers
+	// another host.
+	otherURL := strings.Replace(other.URL, "127.0.0.1", "localhost", 1)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/same" {
+			http.Redirect(w, r, "/echo", http.StatusFound)
+			return
+		}
+		if r.URL.Path == "/echo" {
+			echoHeaders(w, r)
+			return
+		}
+		http.Redirect(w, r, otherURL+"/", http.StatusFound)
+	}))
+	t.Cleanup(s.Close)
+	target := &HTTPTarget{URL: s.URL, HTTPOptions: HTTPOptions{BearerToken: "s3cret"}}
+	var v struct{ Auth, Agent string }
+	if err := target.getJSON(target.url("/other"), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Auth != "" || v.Agent != UserAgent {
+		t.Errorf("other host: got %+v", v)
+	}
+	if err := target.getJSON(target.url("/same"), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Auth != "Bearer s3cret" {
+		t.Errorf("same host: got %+v", v)
+	}
+}
+
+func TestHTTPTargetStatus(t *testing.T) {
+	s := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(s.Close)
+	target := &HTTPTarget{URL: s.URL}
+	if target.enable(target.url("/")) {
+		t.Error("enabled on 404")
+	}
+	err := target.getJSON(target.url("/stats"), nil)
+	if err == nil || err.Error() != s.URL+"/stats: 404 Not Found" {
+		t.Errorf("got %v", err)
+	}
+	target.URL = strings.Replace(s.URL, "://", "://monitor:s3cret@", 1)
+	err = target.getJSON(target.url("/stats"), nil)
+	if err == nil || strings.Contains(err.Error(), "s3cret") {
+		t.Errorf("got %v, expected a redacted URL", err)
+	}
+	target.Password = "env:ES_MISSING"
+	target.Username = "monitor"
+	if err := target.getJSON(target.url("/"), nil); err == nil || !strings.Contains(err.Error(), "env:ES_MISSING") {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestHTTPTargetTimeout(t *testing.T) {
+	done := make(chan struct{})
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	t.Cleanup(s.Close)
+	t.Cleanup(func() { close(done) })
+	target := &HTTPTarget{URL: s.URL, HTTPOptions: HTTPOptions{Timeout: time.Millisecond * 50}}
+	if _, err := target.get(s.URL); err == nil || !strings.Contains(err.Error(), "Timeout") {
+		t.Errorf("got %v, expected a timeout", err)
+	}
+}
+
+func TestHTTPTargetProxy(t *testing.T) {
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%q", r.URL.String())
+	}))
+	t.Cleanup(proxy.Close)
+	target := &HTTPTarget{URL: "http://es.invalid:9200", HTTPOptions: HTTPOptions{Proxy: proxy.URL}}
+	var v string
+	if err := target.getJSON(target.url("/_nodes"), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v != "http://es.invalid:9200/_nodes" {
+		t.Errorf("proxy got %q", v)
+	}
+	target.Proxy = "direct"
+	if err := target.getJSON(target.url("/_nodes"), &v); err == nil {
+		t.Error("direct: expected an error")
+	}
+}
+
+func TestHTTPTargetTLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeCert(t, dir, "client")
+	clientCAs := x509.NewCertPool()
+	b, err := os.ReadFile(certFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientCAs.AppendCertsFromPEM(b)
+	s := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%q", r.TLS.PeerCertificates[0].Subject.CommonName)
+	}))
+	s.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	s.StartTLS()
+	t.Cleanup(s.Close)
+	caFile := filepath.Join(dir, "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.Certificate().Raw})
+	if err := os.WriteFile(caFile, ca, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	target := &HTTPTarget{URL: s.URL}
+	var v string
+	if err := target.getJSON(s.URL, &v); err == nil || !strings.Contains(err.Error(), "certificate") {
+		t.Errorf("without CA: got %v", err)
+	}
+	target.CAFile = caFile
+	if err := target.getJSON(s.URL, &v); err == nil {
+		t.Error("without client certificate: expected an error")
+	}
+	target.CertFile, target.KeyFile = certFile, keyFile
+	if err := target.getJSON(s.URL, &v); err != nil || v != "client" {
+		t.Errorf("got %q, %v", v, err)
+	}
+	target.CAFile = ""
+	target.InsecureSkipVerify = true
+	if err := target.getJSON(s.URL, &v); err != nil {
+		t.Errorf("insecure: %v", err)
+	}
+	target.CAFile = keyFile
+	if _, err := target.Client(); err == nil || !strings.Contains(err.Error(), "no certificates") {
+		t.Errorf("CA file of a key: got %v", err)
+	}
+}
+
+// writeCert writes a self-signed client certificate named cn and its key to
+// dir.
+func writeCert(t *testing.T, dir, cn string) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile = filepath.Join(dir, cn+".pem")
+	keyFile = filepath.Join(dir, cn+"-key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
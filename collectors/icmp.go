@@ -7,8 +7,8 @@ import (
 
 	"github.com/oliveagle/go-collectors/datapoint"
 	"github.com/oliveagle/go-collectors/metadata"
+	"github.com/oliveagle/go-collectors/util"
 	"github.com/tatsushid/go-fastping"
-	// "github.com/oliveagle/go-collectors/util"
 )
 
 type response struct {
@@ -16,13 +16,15 @@ type response struct {
 	rtt  time.Duration
 }
 
-// ICMP registers an ICMP collector a given host.
+// ICMP registers an ICMP collector a given host. Its datapoints are tagged
+// with the pinged host as host and dst_host.
 func ICMP(host string) {
 	collectors = append(collectors, &IntervalCollector{
 		F: func() (datapoint.MultiDataPoint, error) {
 			return c_icmp(host)
 		},
-		name: fmt.Sprintf("icmp-%s", host),
+		name:   fmt.Sprintf("icmp-%s", host),
+		remote: true,
 	})
 }
 
@@ -35,14 +37,15 @@ func c_icmp(host string) (datapoint.MultiDataPoint, error) {
 	}
 	p.AddIPAddr(ra)
 	p.MaxRTT = time.Second * 5
+	tags := datapoint.TagSet{"host": util.Clean(host), "dst_host": host}
 	timeout := 1
 	p.OnRecv = func(addr *net.IPAddr, t time.Duration) {
-		Add(&md, "ping.rtt", float64(t)/float64(time.Millisecond), datapoint.TagSet{"dst_host": host}, metadata.Unknown, metadata.None, "")
+		Add(&md, "ping.rtt", float64(t)/float64(time.Millisecond), tags, metadata.Unknown, metadata.None, "")
 		timeout = 0
 	}
 	if err := p.Run(); err != nil {
 		return nil, err
 	}
-	Add(&md, "ping.timeout", timeout, datapoint.TagSet{"dst_host": host}, metadata.Unknown, metadata.None, "")
+	Add(&md, "ping.timeout", timeout, tags, metadata.Unknown, metadata.None, "")
 	return md, nil
 }
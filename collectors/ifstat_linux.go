@@ -6,7 +6,6 @@ import (
 
 	"github.com/oliveagle/go-collectors/datapoint"
 	"github.com/oliveagle/go-collectors/metadata"
-	"github.com/oliveagle/go-collectors/util"
 )
 
 func init() {
@@ -44,7 +43,7 @@ func c_ipcount_linux() (datapoint.MultiDataPoint, error) {
 	var md datapoint.MultiDataPoint
 	v4c := 0
 	v6c := 0
-	err := util.ReadCommand(func(line string) error {
+	err := readCommand(func(line string) error {
 		tl := strings.TrimSpace(line)
 		if strings.HasPrefix(tl, "inet ") {
 			v4c++
@@ -0,0 +1,17 @@
+package collectors
+
+import (
+	"testing"
+)
+
+func Test_c_ifstat_linux_golden(t *testing.T) {
+	fx := loadFixture(t, "c_ifstat_linux")
+	md, err := c_ifstat_linux()
+	fx.check(t, md, err)
+}
+
+func Test_c_ipcount_linux_golden(t *testing.T) {
+	fx := loadFixture(t, "c_ipcount_linux")
+	md, err := c_ipcount_linux()
+	fx.check(t, md, err)
+}
@@ -1,7 +1,6 @@
 package collectors
 
 import (
-	"net/http"
 	"reflect"
 	"runtime"
 	"sync"
@@ -9,15 +8,21 @@ import (
 
 	"github.com/oliveagle/go-collectors/datapoint"
 	// "github.com/oliveagle/go-collectors/metadata"
-	"github.com/oliveagle/go-collectors/slog"
 )
 
 type IntervalCollector struct {
 	F        func() (datapoint.MultiDataPoint, error)
 	Interval time.Duration // defaults to DefaultFreq if unspecified
 	Enable   func() bool
-	name     string
-	init     func()
+	// ErrorInterval is the interval between summaries of repeated errors
+	// of F. Defaults to DefaultErrorInterval; if negative, every error is
+	// logged.
+	ErrorInterval time.Duration
+	name          string
+	init          func()
+	// remote is set by collectors that poll another host; see
+	// RemoteCollector.
+	remote bool
 
 	// internal use
 	sync.Mutex
@@ -34,7 +39,7 @@ func (c *IntervalCollector) Run(dpchan chan<- *datapoint.DataPoint) {
 	if c.Enable != nil {
 		go func() {
 			for {
-				next := time.After(time.Minute * 5)
+				next := Clock.After(time.Minute * 5)
 				c.Lock()
 				c.enabled = c.Enable()
 				c.Unlock()
@@ -42,17 +47,25 @@ func (c *IntervalCollector) Run(dpchan chan<- *datapoint.DataPoint) {
 			}
 		}()
 	}
+	log := logger(c)
+	errs := newErrorLimiter(c.ErrorInterval)
 	for {
 		interval := c.Interval
 		if interval == 0 {
 			interval = DefaultFreq
 		}
-		next := time.After(interval)
+		next := Clock.After(interval)
 		if c.Enabled() {
 			md, err := c.F()
 			if err != nil {
-				slog.Errorf("%v: %v", c.Name(), err)
+				errs.Error(log, err)
+			} else {
+				errs.Success(log)
 			}
+			if c.remote {
+				removeLocalHost(md)
+			}
+			md = Pipeline.Process(c.Name(), md)
 			for _, dp := range md {
 				dpchan <- dp
 			}
@@ -70,6 +83,11 @@ func (c *IntervalCollector) Enabled() bool {
 	return c.enabled
 }
 
+// Remote reports whether c polls another host.
+func (c *IntervalCollector) Remote() bool {
+	return c.remote
+}
+
 func (c *IntervalCollector) Name() string {
 	if c.name != "" {
 		return c.name
@@ -77,14 +95,3 @@ func (c *IntervalCollector) Name() string {
 	v := runtime.FuncForPC(reflect.ValueOf(c.F).Pointer())
 	return v.Name()
 }
-
-func enableURL(url string) func() bool {
-	return func() bool {
-		resp, err := http.Get(url)
-		if err != nil {
-			return false
-		}
-		resp.Body.Close()
-		return resp.StatusCode == 200
-	}
-}
@@ -0,0 +1,61 @@
+package collectors
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/oliveagle/go-collectors/datapoint"
+	"github.com/oliveagle/go-collectors/metadata"
+	"github.com/oliveagle/go-collectors/util"
+)
+
+func TestIntervalCollectorRun(t *testing.T) {
+	clock := util.NewFakeClock(fixtureTime)
+	setClock(t, clock)
+	var enable atomic.Bool
+	var enables atomic.Int32
+	c := &IntervalCollector{
+		F: func() (datapoint.MultiDataPoint, error) {
+			var md datapoint.MultiDataPoint
+			Add(&md, "test.interval", 1, nil, metadata.Gauge, metadata.Count, "")
+			return md, nil
+		},
+		Interval: time.Minute * 2,
+		Enable: func() bool {
+			enables.Add(1)
+			return enable.Load()
+		},
+	}
+	dpchan := make(chan *datapoint.DataPoint, 1)
+	go c.Run(dpchan)
+	// sleep waits for Run and the re-evaluation of Enable to go to sleep.
+	sleep := func(enabled int32) {
+		clock.BlockUntil(2)
+		for enables.Load() < enabled {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	// Runs at 0, 2m and 4m are disabled; Enable is evaluated again at 5m.
+	sleep(1)
+	clock.Advance(time.Minute * 2)
+	sleep(1)
+	clock.Advance(time.Minute * 2)
+	sleep(1)
+	enable.Store(true)
+	clock.Advance(time.Minute)
+	sleep(2)
+	if len(dpchan) != 0 {
+		t.Fatal("disabled collector sent a datapoint")
+	}
+	// Enabled runs at 6m and 8m take their time from the clock.
+	for _, d := range []time.Duration{time.Minute, time.Minute * 2} {
+		clock.Advance(d)
+		dp := <-dpchan
+		if now := clock.Now(); !dp.Timestamp.Equal(now) {
+			t.Errorf("got timestamp %v, expected %v", dp.Timestamp, now)
+		}
+		sleep(2)
+	}
+}
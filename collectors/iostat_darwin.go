@@ -7,7 +7,6 @@ import (
 
 	"github.com/oliveagle/go-collectors/datapoint"
 	"github.com/oliveagle/go-collectors/metadata"
-	"github.com/oliveagle/go-collectors/util"
 )
 
 func init() {
@@ -19,7 +18,7 @@ func c_iostat_darwin() (datapoint.MultiDataPoint, error) {
 	var md datapoint.MultiDataPoint
 	ln := 0
 	i := 0
-	util.ReadCommand(func(line string) error {
+	readCommand(func(line string) error {
 		ln++
 		if ln == 1 {
 			categories = strings.Fields(line)
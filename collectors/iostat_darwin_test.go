@@ -2,6 +2,8 @@ package collectors
 
 import (
 	"testing"
+
+	"github.com/oliveagle/go-collectors/datapoint"
 )
 
 func Test_c_iostat_darwin(t *testing.T) {
@@ -12,7 +14,7 @@ func Test_c_iostat_darwin(t *testing.T) {
 	isOk := false
 	for idx := range md {
 		t.Log(md[idx])
-		if md[idx].Metric == "darwin.loadavg_1_min" && md[idx].Value.(float64) > 0 {
+		if md[idx].Metric == "darwin.loadavg_1_min" && md[idx].Value.(datapoint.Value).Float64() > 0 {
 			isOk = true
 		}
 	}
@@ -118,7 +118,7 @@ func nbUnmarhsall(reader *csv.Reader, v interface{}) error {
 func c_netbackup_jobs() (datapoint.MultiDataPoint, error) {
 	var md datapoint.MultiDataPoint
 	latest := make(map[string]nbJob)
-	if err := util.ReadCommand(func(line string) error {
+	if err := readCommand(func(line string) error {
 		if len(line) < 32 {
 			return nil
 		}
@@ -145,7 +145,7 @@ func c_netbackup_jobs() (datapoint.MultiDataPoint, error) {
 	} else if err != nil {
 		return nil, err
 	}
-	now := time.Now()
+	now := Clock.Now()
 	for _, r := range latest {
 		tags := datapoint.TagSet{"class": r.Class, "client": r.Client, "schedule": r.Schedule}
 		Add(&md, "netbackup.backup.status", r.Status, tags, metadata.Gauge, metadata.StatusCode, "")
@@ -162,7 +162,7 @@ func c_netbackup_frequency() (datapoint.MultiDataPoint, error) {
 	var md datapoint.MultiDataPoint
 	var class, schedule string
 	var clients []string
-	if err := util.ReadCommand(func(line string) error {
+	if err := readCommand(func(line string) error {
 		if strings.HasPrefix(line, "Policy Name:") {
 			clients = nil
 			f := strings.Fields(line)
@@ -0,0 +1,17 @@
+package collectors
+
+import (
+	"testing"
+)
+
+func Test_c_netbackup_jobs_golden(t *testing.T) {
+	fx := loadFixture(t, "c_netbackup_jobs")
+	md, err := c_netbackup_jobs()
+	fx.check(t, md, err)
+}
+
+func Test_c_netbackup_frequency_golden(t *testing.T) {
+	fx := loadFixture(t, "c_netbackup_frequency")
+	md, err := c_netbackup_frequency()
+	fx.check(t, md, err)
+}
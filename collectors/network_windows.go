@@ -94,7 +94,8 @@ func winNetworkInit() {
 	}
 	update()
 	go func() {
-		for _ = range time.Tick(time.Minute * 5) {
+		for {
+			<-Clock.After(time.Minute * 5)
 			update()
 		}
 	}()
@@ -9,7 +9,6 @@ import (
 
 	"github.com/oliveagle/go-collectors/datapoint"
 	"github.com/oliveagle/go-collectors/metadata"
-	"github.com/oliveagle/go-collectors/util"
 )
 
 func init() {
@@ -53,7 +52,7 @@ func ntpUnPretty(s string) (int64, error) {
 func c_ntp_peers_unix() (datapoint.MultiDataPoint, error) {
 	var md datapoint.MultiDataPoint
 	const metric = "ntp."
-	_ = util.ReadCommand(func(line string) error {
+	_ = readCommand(func(line string) error {
 		fields := strings.Fields(line)
 		if len(fields) != len(ntpNtpqPeerFields) || fields[0] == "remote" {
 			return nil
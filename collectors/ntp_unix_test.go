@@ -0,0 +1,33 @@
+// +build !windows
+
+package collectors
+
+import (
+	"testing"
+)
+
+func TestNtpUnPretty(t *testing.T) {
+	tests := map[string]int64{
+		"12":   12,
+		"64m":  64 * 60,
+		"2h":   2 * 60 * 60,
+		"3d":   3 * 24 * 60 * 60,
+		"1024": 1024,
+	}
+	for s, expected := range tests {
+		if i, err := ntpUnPretty(s); err != nil || i != expected {
+			t.Errorf("%s: got %v, %v", s, i, err)
+		}
+	}
+	for _, s := range []string{"", "-", "2x"} {
+		if _, err := ntpUnPretty(s); err == nil {
+			t.Errorf("%q: expected error", s)
+		}
+	}
+}
+
+func Test_c_ntp_peers_unix_golden(t *testing.T) {
+	fx := loadFixture(t, "c_ntp_peers_unix")
+	md, err := c_ntp_peers_unix()
+	fx.check(t, md, err)
+}
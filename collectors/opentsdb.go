@@ -1,31 +1,35 @@
 package collectors
 
 import (
-	"encoding/json"
-	"net/http"
-
 	"github.com/oliveagle/go-collectors/datapoint"
 	"github.com/oliveagle/go-collectors/metadata"
 	// "github.com/oliveagle/go-collectors/util"
 )
 
 func init() {
-	collectors = append(collectors, &IntervalCollector{F: c_opentsdb, Enable: enableURL(tsdbURL)})
+	collectors = append(collectors, &IntervalCollector{F: c_opentsdb, Enable: enableOpenTSDB})
+}
+
+func enableOpenTSDB() bool {
+	return OpenTSDBTarget.enable(OpenTSDBTarget.url("/api/stats"))
 }
 
-const tsdbURL = "http://localhost:4242/api/stats"
+// tsdbStat is a datapoint of /api/stats, which has a timestamp in seconds
+// and a value in a string.
+type tsdbStat struct {
+	Metric    string           `json:"metric"`
+	Timestamp int64            `json:"timestamp"`
+	Value     datapoint.Value  `json:"value"`
+	Tags      datapoint.TagSet `json:"tags"`
+}
 
 func c_opentsdb() (datapoint.MultiDataPoint, error) {
-	resp, err := http.Get(tsdbURL)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	var md, tmp datapoint.MultiDataPoint
-	if err := json.NewDecoder(resp.Body).Decode(&tmp); err != nil {
+	var stats []tsdbStat
+	if err := OpenTSDBTarget.getJSON(OpenTSDBTarget.url("/api/stats"), &stats); err != nil {
 		return nil, err
 	}
-	for _, v := range tmp {
+	var md datapoint.MultiDataPoint
+	for _, v := range stats {
 		delete(v.Tags, "host")
 		AddTS(&md, v.Metric, v.Timestamp, v.Value, v.Tags, metadata.Unknown, metadata.None, "")
 	}
@@ -0,0 +1,15 @@
+package collectors
+
+import (
+	"testing"
+)
+
+func Test_c_opentsdb_golden(t *testing.T) {
+	fx := loadFixture(t, "c_opentsdb")
+	setString(t, &OpenTSDBTarget.URL, fx.URL)
+	if !enableOpenTSDB() {
+		t.Fatal("not enabled")
+	}
+	md, err := c_opentsdb()
+	fx.check(t, md, err)
+}
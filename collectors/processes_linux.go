@@ -2,9 +2,6 @@ package collectors
 
 import (
 	"fmt"
-	"io/ioutil"
-	"os"
-
 	"strconv"
 	"strings"
 
@@ -26,28 +23,22 @@ func WatchProcesses(procs []*WatchedProc) error {
 func linuxProcMonitor(w *WatchedProc, md *datapoint.MultiDataPoint) error {
 	var err error
 	for pid, id := range w.Processes {
-		stats_file, e := ioutil.ReadFile("/proc/" + pid + "/stat")
-		if e != nil {
-			w.Remove(pid)
-			continue
-		}
-		io_file, e := ioutil.ReadFile("/proc/" + pid + "/io")
+		stats_file, e := readFile("/proc/" + pid + "/stat")
 		if e != nil {
 			w.Remove(pid)
 			continue
 		}
-		limits, e := ioutil.ReadFile("/proc/" + pid + "/limits")
+		io_file, e := readFile("/proc/" + pid + "/io")
 		if e != nil {
 			w.Remove(pid)
 			continue
 		}
-		fd_dir, e := os.Open("/proc/" + pid + "/fd")
+		limits, e := readFile("/proc/" + pid + "/limits")
 		if e != nil {
 			w.Remove(pid)
 			continue
 		}
-		fds, e := fd_dir.Readdirnames(0)
-		fd_dir.Close()
+		fds, e := readDir("/proc/" + pid + "/fd")
 		if e != nil {
 			w.Remove(pid)
 			continue
@@ -114,7 +105,7 @@ const (
 )
 
 func getLinuxProccesses() ([]*Process, error) {
-	files, err := ioutil.ReadDir("/proc")
+	files, err := readDir("/proc")
 	if err != nil {
 		return nil, err
 	}
@@ -126,7 +117,7 @@ func getLinuxProccesses() ([]*Process, error) {
 	}
 	var lps []*Process
 	for _, pid := range pids {
-		cmdline, err := ioutil.ReadFile("/proc/" + pid + "/cmdline")
+		cmdline, err := readFile("/proc/" + pid + "/cmdline")
 		if err != nil {
 			//Continue because the pid might not exist any more
 			continue
@@ -0,0 +1,19 @@
+package collectors
+
+import (
+	"testing"
+)
+
+func Test_c_linux_processes_golden(t *testing.T) {
+	fx := loadFixture(t, "c_linux_processes")
+	var procs []*WatchedProc
+	for _, watch := range []string{"redis-server,redis,", "sshd,,"} {
+		w, err := NewWatchedProc(watch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		procs = append(procs, w)
+	}
+	md, err := c_linux_processes(procs)
+	fx.check(t, md, err)
+}
@@ -2,7 +2,6 @@ package collectors
 
 import (
 	"fmt"
-	"io/ioutil"
 	"regexp"
 	"strconv"
 	"strings"
@@ -334,7 +333,7 @@ func c_procstats_linux() (datapoint.MultiDataPoint, error) {
 		Error = err
 	}
 	const bondingPath = "/proc/net/bonding"
-	bondDevices, _ := ioutil.ReadDir(bondingPath)
+	bondDevices, _ := readDir(bondingPath)
 	for _, fi := range bondDevices {
 		var iface string
 		var slave_count int
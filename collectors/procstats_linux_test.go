@@ -1,8 +1,11 @@
 // +build linux
+
 package collectors
 
 import (
 	"testing"
+
+	"github.com/oliveagle/go-collectors/datapoint"
 )
 
 func Test_c_procstats_linux(t *testing.T) {
@@ -16,7 +19,7 @@ func Test_c_procstats_linux(t *testing.T) {
 	isOsMemTotalOk := false
 	for idx := range md {
 		// t.Log(md[idx])
-		if md[idx].Metric == osMemTotal && md[idx].Value.(int) > 0 {
+		if md[idx].Metric == osMemTotal && md[idx].Value.(datapoint.Value).Float64() > 0 {
 			isOsMemTotalOk = true
 		}
 	}
@@ -27,3 +30,18 @@ func Test_c_procstats_linux(t *testing.T) {
 	// t.Log(collectors)
 	// t.Error("hhh")
 }
+
+func Benchmark_c_procstats_linux(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := c_procstats_linux(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func Test_c_procstats_linux_golden(t *testing.T) {
+	fx := loadFixture(t, "c_procstats_linux")
+	md, err := c_procstats_linux()
+	fx.check(t, md, err)
+}
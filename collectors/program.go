@@ -2,6 +2,7 @@ package collectors
 
 import (
 	"bufio"
+	"errors"
 	"io"
 	"os"
 	"os/exec"
@@ -9,17 +10,24 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/oliveagle/go-collectors/datapoint"
 	// "github.com/oliveagle/go-collectors/metadata"
 	"github.com/oliveagle/go-collectors/slog"
-	"github.com/oliveagle/go-collectors/util"
 )
 
 type ProgramCollector struct {
 	Path     string
 	Interval time.Duration
+	// ErrorInterval is the interval between summaries of repeated errors,
+	// such as bad output lines. Defaults to DefaultErrorInterval; if
+	// negative, every error is logged.
+	ErrorInterval time.Duration
+
+	errsOnce sync.Once
+	errs     *errorLimiter
 }
 
 func InitPrograms(cpath string) {
@@ -79,28 +87,39 @@ func isExecutable(f os.FileInfo) bool {
 }
 
 func (c *ProgramCollector) Run(dpchan chan<- *datapoint.DataPoint) {
-	if c.Interval == 0 {
-		for {
-			next := time.After(DefaultFreq)
-			if err := c.runProgram(dpchan); err != nil {
-				slog.Infoln(err)
-			}
-			<-next
-			slog.Infoln("restarting", c.Path)
+	log := logger(c)
+	interval := c.Interval
+	if interval == 0 {
+		interval = DefaultFreq
+	}
+	for {
+		next := Clock.After(interval)
+		n := c.limiter().Count()
+		if err := c.runProgram(dpchan); err != nil {
+			c.limiter().Error(log, err)
+		} else if c.limiter().Count() == n {
+			c.limiter().Success(log)
 		}
-	} else {
-		for {
-			next := time.After(c.Interval)
-			c.runProgram(dpchan)
-			<-next
+		<-next
+		if c.Interval == 0 {
+			log.Info("restarting program")
 		}
 	}
 }
 
+// limiter returns the errorLimiter of c.
+func (c *ProgramCollector) limiter() *errorLimiter {
+	c.errsOnce.Do(func() {
+		c.errs = newErrorLimiter(c.ErrorInterval)
+	})
+	return c.errs
+}
+
 func (c *ProgramCollector) Init() {
 }
 
 func (c *ProgramCollector) runProgram(dpchan chan<- *datapoint.DataPoint) (progError error) {
+	log, errs := logger(c), c.limiter()
 	cmd := exec.Command(c.Path)
 	pr, pw := io.Pipe()
 	s := bufio.NewScanner(pr)
@@ -119,7 +138,7 @@ func (c *ProgramCollector) runProgram(dpchan chan<- *datapoint.DataPoint) (progE
 		es := bufio.NewScanner(er)
 		for es.Scan() {
 			line := strings.TrimSpace(es.Text())
-			slog.Error(line)
+			errs.Error(log, errors.New(line))
 		}
 	}()
 Loop:
@@ -127,41 +146,43 @@ Loop:
 		line := strings.TrimSpace(s.Text())
 		sp := strings.Fields(line)
 		if len(sp) < 3 {
-			slog.Errorf("bad line in program %s: %s", c.Path, line)
+			errs.Errorf(log, "bad line: %s", line)
 			continue
 		}
 		ts, err := strconv.ParseInt(sp[1], 10, 64)
 		if err != nil {
-			slog.Errorf("bad timestamp in program %s: %s", c.Path, sp[1])
+			errs.Errorf(log, "bad timestamp: %s", sp[1])
 			continue
 		}
-		val, err := strconv.ParseFloat(sp[2], 64)
+		val, err := datapoint.ParseValue(sp[2])
 		if err != nil {
-			slog.Errorf("bad value in program %s: %s", c.Path, sp[2])
+			errs.Errorf(log, "bad value: %s", sp[2])
 			continue
 		}
 		if !datapoint.ValidTag(sp[0]) {
-			slog.Errorf("bad metric in program %s: %s", c.Path, sp[0])
+			errs.Errorf(log, "bad metric: %s", sp[0])
 		}
 		dp := datapoint.DataPoint{
 			Metric:    sp[0],
-			Timestamp: ts,
+			Timestamp: time.Unix(ts, 0),
 			Value:     val,
-			Tags:      datapoint.TagSet{"host": util.Hostname},
 		}
+		tags := make(datapoint.TagSet)
 		for _, tag := range sp[3:] {
-			tags, err := datapoint.ParseTags(tag)
-			if v, ok := tags["host"]; ok && v == "" {
-				delete(dp.Tags, "host")
+			t, err := datapoint.ParseTags(tag)
+			if v, ok := t["host"]; ok && v == "" {
+				tags["host"] = ""
 			} else if err != nil {
-				slog.Errorf("bad tag in program %s, metric %s: %v: %v", c.Path, sp[0], tag, err)
+				errs.Errorf(log, "bad tag for %s: %v: %v", sp[0], tag, err)
 				continue Loop
 			} else {
-				dp.Tags.Merge(tags)
+				tags.Merge(t)
 			}
 		}
-		dp.Tags = AddTags.Copy().Merge(dp.Tags)
-		dpchan <- &dp
+		dp.Tags = defaultTags().Merge(tags)
+		for _, d := range Pipeline.Process(c.Name(), datapoint.MultiDataPoint{&dp}) {
+			dpchan <- d
+		}
 	}
 	if err := s.Err(); err != nil {
 		return err
@@ -1,15 +1,12 @@
 package collectors
 
 import (
-	"encoding/json"
-	"net/http"
 	"regexp"
 	"strings"
 	"time"
 
 	"github.com/oliveagle/go-collectors/datapoint"
 	"github.com/oliveagle/go-collectors/metadata"
-	"github.com/oliveagle/go-collectors/util"
 )
 
 func init() {
@@ -24,7 +21,7 @@ var (
 func parseRailURL() string {
 	var config string
 	var url string
-	util.ReadCommand(func(line string) error {
+	readCommand(func(line string) error {
 		fields := strings.Fields(line)
 		if len(fields) == 0 || !strings.Contains(fields[0], "rg-listener") {
 			return nil
@@ -49,20 +46,16 @@ func parseRailURL() string {
 }
 
 func enableRailgun() bool {
-	rgURL = parseRailURL()
-	return enableURL(rgURL)()
+	if rgURL = RailgunTarget.URL; rgURL == "" {
+		rgURL = parseRailURL()
+	}
+	return RailgunTarget.enable(rgURL)
 }
 
 func c_railgun() (datapoint.MultiDataPoint, error) {
 	var md datapoint.MultiDataPoint
-	res, err := http.Get(rgURL)
-	if err != nil {
-		return nil, err
-	}
-	defer res.Body.Close()
 	var r map[string]interface{}
-	j := json.NewDecoder(res.Body)
-	if err := j.Decode(&r); err != nil {
+	if err := RailgunTarget.getJSON(rgURL, &r); err != nil {
 		return nil, err
 	}
 	for k, v := range r {
@@ -0,0 +1,22 @@
+package collectors
+
+import (
+	"testing"
+)
+
+func TestParseRailURL(t *testing.T) {
+	loadFixture(t, "railgun")
+	if url := parseRailURL(); url != "http://127.0.0.1:24088" {
+		t.Errorf("got %q", url)
+	}
+}
+
+func Test_c_railgun_golden(t *testing.T) {
+	fx := loadFixture(t, "railgun")
+	setString(t, &RailgunTarget.URL, fx.URL)
+	if !enableRailgun() {
+		t.Fatal("not enabled")
+	}
+	md, err := c_railgun()
+	fx.check(t, md, err)
+}
@@ -4,7 +4,6 @@ package collectors
 
 import (
 	"fmt"
-	"io/ioutil"
 	"regexp"
 	"strings"
 	"sync"
@@ -14,7 +13,6 @@ import (
 
 	"github.com/oliveagle/go-collectors/datapoint"
 	"github.com/oliveagle/go-collectors/metadata"
-	"github.com/oliveagle/go-collectors/util"
 )
 
 func init() {
@@ -95,76 +93,84 @@ var (
 
 func redisInit() {
 	update := func() {
-		ri := make(map[string]string)
-		oldRedis := false
-		add := func(port, pid string) {
-			cluster := fmt.Sprintf("port-%s", port)
-			defer func() {
-				ri[port] = cluster
-			}()
-			f, err := ioutil.ReadFile(fmt.Sprintf("/proc/%s/cmdline", pid))
-			if err != nil {
-				return
-			}
-			fsp := strings.Split(strings.Split(string(f), "\n")[0], "\u0000")
-			if len(fsp) < 2 {
-				return
-			}
-			cfg := fsp[len(fsp)-2]
-			if len(cfg) == 0 {
-				return
-			}
-			readLine(cfg, func(cfgline string) error {
-				result := tcRE.FindStringSubmatch(cfgline)
-				if len(result) > 2 && strings.ToLower(result[0]) == "cluster" {
-					cluster = strings.ToLower(result[1])
-				}
-				return nil
-			})
-		}
-		util.ReadCommand(func(line string) error {
-			sp := strings.Fields(line)
-			if len(sp) != 3 || !strings.HasSuffix(sp[1], "redis-server") {
-				return nil
-			}
-			if !strings.Contains(sp[2], ":") {
-				oldRedis = true
-				return nil
-			}
-			pid := sp[0]
-			port := strings.Split(sp[2], ":")[1]
-			if port != "0" {
-				add(port, pid)
-			}
-			return nil
-		}, "ps", "-e", "-o", "pid,args")
-		if oldRedis {
-			util.ReadCommand(func(line string) error {
-				if !strings.Contains(line, "redis-server") {
-					return nil
-				}
-				sp := strings.Fields(line)
-				if len(sp) < 7 || !strings.Contains(sp[3], ":") {
-					return nil
-				}
-				pid := strings.Split(sp[6], "/")[0]
-				port := strings.Split(sp[3], ":")[1]
-				add(port, pid)
-				return nil
-			}, "netstat", "-tnlp")
-		}
+		ri := redisFindInstances()
 		redisLock.Lock()
 		redisInstances = ri
 		redisLock.Unlock()
 	}
 	update()
 	go func() {
-		for _ = range time.Tick(time.Minute * 5) {
+		for {
+			<-Clock.After(time.Minute * 5)
 			update()
 		}
 	}()
 }
 
+// redisFindInstances returns the cluster names of the local redis servers by
+// port.
+func redisFindInstances() map[string]string {
+	ri := make(map[string]string)
+	oldRedis := false
+	add := func(port, pid string) {
+		cluster := fmt.Sprintf("port-%s", port)
+		defer func() {
+			ri[port] = cluster
+		}()
+		f, err := readFile(fmt.Sprintf("/proc/%s/cmdline", pid))
+		if err != nil {
+			return
+		}
+		fsp := strings.Split(strings.Split(string(f), "\n")[0], "\u0000")
+		if len(fsp) < 2 {
+			return
+		}
+		cfg := fsp[len(fsp)-2]
+		if len(cfg) == 0 {
+			return
+		}
+		readLine(cfg, func(cfgline string) error {
+			result := tcRE.FindStringSubmatch(cfgline)
+			if len(result) > 2 && strings.ToLower(result[1]) == "cluster" {
+				cluster = strings.ToLower(result[2])
+			}
+			return nil
+		})
+	}
+	readCommand(func(line string) error {
+		sp := strings.Fields(line)
+		if len(sp) != 3 || !strings.HasSuffix(sp[1], "redis-server") {
+			return nil
+		}
+		if !strings.Contains(sp[2], ":") {
+			oldRedis = true
+			return nil
+		}
+		pid := sp[0]
+		port := strings.Split(sp[2], ":")[1]
+		if port != "0" {
+			add(port, pid)
+		}
+		return nil
+	}, "ps", "-e", "-o", "pid,args")
+	if oldRedis {
+		readCommand(func(line string) error {
+			if !strings.Contains(line, "redis-server") {
+				return nil
+			}
+			sp := strings.Fields(line)
+			if len(sp) < 7 || !strings.Contains(sp[3], ":") {
+				return nil
+			}
+			pid := strings.Split(sp[6], "/")[0]
+			port := strings.Split(sp[3], ":")[1]
+			add(port, pid)
+			return nil
+		}, "netstat", "-tnlp")
+	}
+	return ri
+}
+
 func c_redis() (datapoint.MultiDataPoint, error) {
 	var md datapoint.MultiDataPoint
 	redisLock.Lock()
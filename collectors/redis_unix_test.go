@@ -0,0 +1,20 @@
+// +build darwin linux
+
+package collectors
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRedisFindInstances(t *testing.T) {
+	loadFixture(t, "redis_instances")
+	expected := map[string]string{
+		"6379": "port-6379",
+		"6380": "sessions",
+		"6390": "port-6390",
+	}
+	if got := redisFindInstances(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %v, expected %v", got, expected)
+	}
+}
@@ -1,9 +1,11 @@
 package collectors
 
 import (
+	"encoding/asn1"
 	"fmt"
 	"io"
 	"math/big"
+	"net"
 
 	"github.com/mjibson/snmp"
 )
@@ -17,31 +19,20 @@ func snmp_subtree(host, community, oid string) (map[int]interface{}, error) {
 	}
 	m := make(map[int]interface{})
 	for rows.Next() {
-		switch oid {
-		case ifHCInBroadcastPkts:
-			a := new(big.Int)
-			id, err := rows.Scan(&a)
-			if err != nil {
-				return nil, err
-			}
-			switch t := id.(type) {
-			case int:
-				m[t] = a
-			default:
-				return nil, fmt.Errorf("snmp subtree: only one level allowed")
-			}
+		var raw asn1.RawValue
+		id, err := rows.Scan(&raw)
+		if err != nil {
+			return nil, err
+		}
+		v, err := snmpValue(raw)
+		if err != nil {
+			return nil, err
+		}
+		switch t := id.(type) {
+		case int:
+			m[t] = v
 		default:
-			var a interface{}
-			id, err := rows.Scan(&a)
-			if err != nil {
-				return nil, err
-			}
-			switch t := id.(type) {
-			case int:
-				m[t] = a
-			default:
-				return nil, fmt.Errorf("snmp subtree: only one level allowed")
-			}
+			return nil, fmt.Errorf("snmp subtree: only one level allowed")
 		}
 	}
 	if err := rows.Err(); err != nil && err != io.EOF {
@@ -50,8 +41,31 @@ func snmp_subtree(host, community, oid string) (map[int]interface{}, error) {
 	return m, nil
 }
 
+// snmpValue converts integers of all SNMP types to *big.Int, since
+// Counter64 values may not fit in an int64, and other values to the types
+// of asn1.Unmarshal, such as []byte for strings.
+func snmpValue(raw asn1.RawValue) (interface{}, error) {
+	if raw.Class == asn1.ClassUniversal && raw.Tag == asn1.TagInteger {
+		v := new(big.Int)
+		_, err := asn1.Unmarshal(raw.FullBytes, &v)
+		return v, err
+	}
+	var v interface{}
+	_, err := asn1.Unmarshal(raw.FullBytes, &v)
+	return v, err
+}
+
 func snmp_oid(host, community, oid string) (*big.Int, error) {
 	v := new(big.Int)
 	err := snmp.Get(host, community, oid, &v)
 	return v, err
 }
+
+// snmpHostTag returns the host tag of datapoints of the agent at host, which
+// may include a port.
+func snmpHostTag(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
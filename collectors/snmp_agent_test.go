@@ -0,0 +1,395 @@
+package collectors
+
+import (
+	"bufio"
+	"encoding/asn1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// snmpAgent is an SNMP v1 and v2c agent on a local UDP port, which serves
+// the variables of an snmpwalk dump to end to end tests of SNMP collectors.
+// It answers GET, GETNEXT and GETBULK requests.
+type snmpAgent struct {
+	community string
+	vars      []snmpVar
+	conn      *net.UDPConn
+	// drop makes the agent ignore requests, so that clients time out.
+	drop atomic.Bool
+}
+
+// snmpVar is a variable of an agent with its BER encoded value.
+type snmpVar struct {
+	oid   asn1.ObjectIdentifier
+	value asn1.RawValue
+}
+
+// Values of bindings that are not variables.
+var (
+	snmpNull           = asn1.RawValue{FullBytes: []byte{0x05, 0x00}}
+	snmpNoSuchObject   = asn1.RawValue{FullBytes: []byte{0x80, 0x00}}
+	snmpNoSuchInstance = asn1.RawValue{FullBytes: []byte{0x81, 0x00}}
+	snmpEndOfMibView   = asn1.RawValue{FullBytes: []byte{0x82, 0x00}}
+)
+
+// Error statuses of responses.
+const (
+	snmpNoSuchName         = 2
+	snmpAuthorizationError = 16
+)
+
+// startSNMPAgent serves vars to requests of community until the end of the
+// test. Requests with another community get an authorizationError response.
+func startSNMPAgent(t *testing.T, community string, vars []snmpVar) *snmpAgent {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := &snmpAgent{community: community, vars: vars, conn: conn}
+	t.Cleanup(func() { conn.Close() })
+	go a.serve()
+	return a
+}
+
+// Addr returns the host:port of the agent.
+func (a *snmpAgent) Addr() string {
+	return a.conn.LocalAddr().String()
+}
+
+func (a *snmpAgent) serve() {
+	buf := make([]byte, 65536)
+	for {
+		n, addr, err := a.conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		if a.drop.Load() {
+			continue
+		}
+		if resp := a.handle(buf[:n]); resp != nil {
+			a.conn.WriteToUDP(resp, addr)
+		}
+	}
+}
+
+type snmpMessage struct {
+	Version   int
+	Community []byte
+	PDU       asn1.RawValue
+}
+
+// snmpPDU is a request or response PDU. In GETBULK requests ErrorStatus and
+// ErrorIndex are non-repeaters and max-repetitions.
+type snmpPDU struct {
+	RequestID   int32
+	ErrorStatus int
+	ErrorIndex  int
+	Bindings    []snmpBinding
+}
+
+type snmpBinding struct {
+	Name  asn1.ObjectIdentifier
+	Value asn1.RawValue
+}
+
+type snmpResponse struct {
+	Version   int
+	Community []byte
+	PDU       snmpPDU `asn1:"tag:2"`
+}
+
+// handle returns the response to the request b, or nil if it is invalid.
+func (a *snmpAgent) handle(b []byte) []byte {
+	var m snmpMessage
+	if _, err := asn1.Unmarshal(b, &m); err != nil || m.Version > 1 || len(m.PDU.FullBytes) == 0 {
+		return nil
+	}
+	// Parse the tagged PDU as the SEQUENCE it implicitly is.
+	p := append([]byte(nil), m.PDU.FullBytes...)
+	p[0] = 0x30
+	var req snmpPDU
+	if _, err := asn1.Unmarshal(p, &req); err != nil {
+		return nil
+	}
+	v1 := m.Version == 0
+	resp := snmpPDU{RequestID: req.RequestID}
+	switch {
+	case string(m.Community) != a.community:
+		resp.ErrorStatus = snmpAuthorizationError
+		resp.Bindings = nullBindings(req.Bindings)
+	case m.PDU.Tag == 0:
+		resp.Bindings, resp.ErrorIndex = a.get(req.Bindings, v1)
+	case m.PDU.Tag == 1:
+		resp.Bindings, resp.ErrorIndex = a.getNext(req.Bindings, v1)
+	case m.PDU.Tag == 5 && !v1:
+		resp.Bindings = a.getBulk(req.Bindings, req.ErrorStatus, req.ErrorIndex)
+	default:
+		return nil
+	}
+	if resp.ErrorIndex > 0 {
+		resp.ErrorStatus = snmpNoSuchName
+		resp.Bindings = nullBindings(req.Bindings)
+	}
+	out, err := asn1.Marshal(snmpResponse{Version: m.Version, Community: m.Community, PDU: resp})
+	if err != nil {
+		return nil
+	}
+	return out
+}
+
+func nullBindings(req []snmpBinding) []snmpBinding {
+	bs := make([]snmpBinding, len(req))
+	for i, b := range req {
+		bs[i] = snmpBinding{b.Name, snmpNull}
+	}
+	return bs
+}
+
+// index returns the index of the first variable at or after oid.
+func (a *snmpAgent) index(oid asn1.ObjectIdentifier) int {
+	return sort.Search(len(a.vars), func(i int) bool {
+		return compareOID(a.vars[i].oid, oid) >= 0
+	})
+}
+
+// get returns the variables of req. In v1 the index of the first missing
+// variable, counted from one, is returned instead of an exception.
+func (a *snmpAgent) get(req []snmpBinding, v1 bool) ([]snmpBinding, int) {
+	bs := make([]snmpBinding, len(req))
+	for i, b := range req {
+		bs[i].Name = b.Name
+		j := a.index(b.Name)
+		switch {
+		case j < len(a.vars) && a.vars[j].oid.Equal(b.Name):
+			bs[i].Value = a.vars[j].value
+		case v1:
+			return nil, i + 1
+		case j < len(a.vars) && hasOIDPrefix(a.vars[j].oid, b.Name[:len(b.Name)-1]):
+			// The object exists, with other instances.
+			bs[i].Value = snmpNoSuchInstance
+		default:
+			bs[i].Value = snmpNoSuchObject
+		}
+	}
+	return bs, 0
+}
+
+// next returns the binding of the variable after oid.
+func (a *snmpAgent) next(oid asn1.ObjectIdentifier) (snmpBinding, bool) {
+	j := a.index(oid)
+	if j < len(a.vars) && a.vars[j].oid.Equal(oid) {
+		j++
+	}
+	if j == len(a.vars) {
+		return snmpBinding{oid, snmpEndOfMibView}, false
+	}
+	return snmpBinding{a.vars[j].oid, a.vars[j].value}, true
+}
+
+func (a *snmpAgent) getNext(req []snmpBinding, v1 bool) ([]snmpBinding, int) {
+	bs := make([]snmpBinding, len(req))
+	for i, b := range req {
+		var ok bool
+		if bs[i], ok = a.next(b.Name); !ok && v1 {
+			return nil, i + 1
+		}
+	}
+	return bs, 0
+}
+
+// getBulk answers a GETNEXT for the first nonRepeaters bindings, and up to
+// maxRepetitions successive GETNEXTs for the others, as in RFC 3416 4.2.3.
+func (a *snmpAgent) getBulk(req []snmpBinding, nonRepeaters, maxRepetitions int) []snmpBinding {
+	if nonRepeaters > len(req) {
+		nonRepeaters = len(req)
+	}
+	bs, _ := a.getNext(req[:nonRepeaters], false)
+	last := make([]asn1.ObjectIdentifier, 0, len(req)-nonRepeaters)
+	for _, b := range req[nonRepeaters:] {
+		last = append(last, b.Name)
+	}
+	for r := 0; r < maxRepetitions && len(last) > 0; r++ {
+		end := true
+		for i, oid := range last {
+			b, ok := a.next(oid)
+			bs = append(bs, b)
+			last[i] = b.Name
+			end = end && !ok
+		}
+		if end {
+			break
+		}
+	}
+	return bs
+}
+
+func compareOID(a, b asn1.ObjectIdentifier) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return len(a) - len(b)
+}
+
+func hasOIDPrefix(oid, prefix asn1.ObjectIdentifier) bool {
+	return len(oid) >= len(prefix) && oid[:len(prefix)].Equal(prefix)
+}
+
+// loadSNMPWalk reads the snmpwalk dump name.
+func loadSNMPWalk(t *testing.T, name string) []snmpVar {
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	vars, err := parseSNMPWalk(f)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	return vars
+}
+
+// parseSNMPWalk parses the output of snmpwalk -On, a line per variable such
+// as:
+//
+//	.1.3.6.1.2.1.31.1.1.1.1.1 = STRING: "Gi0/1"
+//	.1.3.6.1.2.1.31.1.1.1.6.1 = Counter64: 18446744073709551615
+//
+// Variables are sorted by OID. Empty lines and those starting with # are
+// skipped.
+func parseSNMPWalk(r io.Reader) ([]snmpVar, error) {
+	var vars []snmpVar
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, " = ")
+		if !ok {
+			return nil, fmt.Errorf("line %d: missing =", n)
+		}
+		oid, err := parseOID(name)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		}
+		v, err := parseSNMPValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		}
+		vars = append(vars, snmpVar{oid, v})
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	sort.Slice(vars, func(i, j int) bool { return compareOID(vars[i].oid, vars[j].oid) < 0 })
+	return vars, nil
+}
+
+func parseOID(s string) (asn1.ObjectIdentifier, error) {
+	var oid asn1.ObjectIdentifier
+	for _, f := range strings.Split(strings.TrimPrefix(s, "."), ".") {
+		i, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, fmt.Errorf("bad OID %q", s)
+		}
+		oid = append(oid, i)
+	}
+	if len(oid) < 2 {
+		return nil, fmt.Errorf("bad OID %q", s)
+	}
+	return oid, nil
+}
+
+// Application tags of SNMP types.
+var snmpAppTags = map[string]byte{
+	"IpAddress":  0,
+	"Counter32":  1,
+	"Gauge32":    2,
+	"Unsigned32": 2,
+	"Timeticks":  3,
+	"Counter64":  6,
+}
+
+// parseSNMPValue encodes the value of a line of snmpwalk, such as
+// "INTEGER: up(1)" or "Timeticks: (12345) 0:02:03.45".
+func parseSNMPValue(s string) (asn1.RawValue, error) {
+	typ, val, ok := strings.Cut(s, ": ")
+	if !ok {
+		if s == `""` {
+			typ, val = "STRING", s
+		} else {
+			typ = strings.TrimSuffix(s, ":")
+		}
+	}
+	// Enumerations and time ticks show the number in parentheses.
+	if i := strings.Index(val, "("); i >= 0 && (typ == "INTEGER" || typ == "Timeticks") {
+		if j := strings.Index(val[i:], ")"); j > 0 {
+			val = val[i+1 : i+j]
+		}
+	}
+	var b []byte
+	var err error
+	switch typ {
+	case "INTEGER":
+		var i int64
+		if i, err = strconv.ParseInt(val, 10, 32); err == nil {
+			b, err = asn1.Marshal(i)
+		}
+	case "STRING":
+		if uq, err := strconv.Unquote(val); err == nil {
+			val = uq
+		}
+		b, err = asn1.Marshal([]byte(val))
+	case "Hex-STRING":
+		var h []byte
+		if h, err = hex.DecodeString(strings.ReplaceAll(val, " ", "")); err == nil {
+			b, err = asn1.Marshal(h)
+		}
+	case "OID":
+		var oid asn1.ObjectIdentifier
+		if oid, err = parseOID(val); err == nil {
+			b, err = asn1.Marshal(oid)
+		}
+	case "IpAddress":
+		ip := net.ParseIP(val).To4()
+		if ip == nil {
+			return asn1.RawValue{}, fmt.Errorf("bad IpAddress %q", val)
+		}
+		b = append([]byte{0x40, 4}, ip...)
+	case "Counter32", "Gauge32", "Unsigned32", "Timeticks", "Counter64":
+		bits := 32
+		if typ == "Counter64" {
+			bits = 64
+		}
+		var u uint64
+		if u, err = strconv.ParseUint(val, 10, bits); err == nil {
+			// Encoded as an INTEGER, which is unsigned by a leading zero
+			// byte where needed, with an application tag.
+			if b, err = asn1.Marshal(new(big.Int).SetUint64(u)); err == nil {
+				b[0] = 0x40 | snmpAppTags[typ]
+			}
+		}
+	case "NULL":
+		b = snmpNull.FullBytes
+	default:
+		return asn1.RawValue{}, fmt.Errorf("unsupported type %q", typ)
+	}
+	if err != nil {
+		return asn1.RawValue{}, fmt.Errorf("%s: %v", typ, err)
+	}
+	return asn1.RawValue{FullBytes: b}, nil
+}
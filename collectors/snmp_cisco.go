@@ -7,6 +7,7 @@ import (
 
 	"github.com/oliveagle/go-collectors/datapoint"
 	"github.com/oliveagle/go-collectors/metadata"
+	"github.com/oliveagle/go-collectors/util"
 )
 
 const (
@@ -17,13 +18,18 @@ const (
 )
 
 // SNMPCisco registers a SNMP CISCO collector for the given community and host.
-func SNMPCisco(community, host string) {
+func SNMPCisco(community util.Secret, host string) {
 	collectors = append(collectors, &IntervalCollector{
 		F: func() (datapoint.MultiDataPoint, error) {
-			return c_snmp_cisco(community, host)
+			c, err := community.Value()
+			if err != nil {
+				return nil, err
+			}
+			return c_snmp_cisco(c, host)
 		},
 		Interval: time.Second * 30,
 		name:     fmt.Sprintf("snmp-cisco-%s", host),
+		remote:   true,
 	})
 }
 
@@ -36,7 +42,7 @@ func c_snmp_cisco(community, host string) (datapoint.MultiDataPoint, error) {
 	} else {
 		return nil, err
 	}
-	Add(&md, "cisco.cpu", v.String(), datapoint.TagSet{"host": host}, metadata.Gauge, metadata.Pct, "The overall CPU busy percentage in the last five-second period.")
+	Add(&md, "cisco.cpu", v.String(), datapoint.TagSet{"host": snmpHostTag(host)}, metadata.Gauge, metadata.Pct, "The overall CPU busy percentage in the last five-second period.")
 	names, err := snmp_subtree(host, community, ciscoMemName)
 	if err != nil {
 		return nil, err
@@ -59,8 +65,8 @@ func c_snmp_cisco(community, host string) (datapoint.MultiDataPoint, error) {
 		if !present {
 			continue
 		}
-		Add(&md, "cisco.mem.used", u, datapoint.TagSet{"host": host, "name": n}, metadata.Unknown, metadata.None, "")
-		Add(&md, "cisco.mem.free", f, datapoint.TagSet{"host": host, "name": n}, metadata.Unknown, metadata.None, "")
+		Add(&md, "cisco.mem.used", u, datapoint.TagSet{"host": snmpHostTag(host), "name": n}, metadata.Unknown, metadata.None, "")
+		Add(&md, "cisco.mem.free", f, datapoint.TagSet{"host": snmpHostTag(host), "name": n}, metadata.Unknown, metadata.None, "")
 	}
 	return md, nil
 }
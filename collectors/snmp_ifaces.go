@@ -7,7 +7,7 @@ import (
 
 	"github.com/oliveagle/go-collectors/datapoint"
 	"github.com/oliveagle/go-collectors/metadata"
-	// "github.com/oliveagle/go-collectors/util"
+	"github.com/oliveagle/go-collectors/util"
 )
 
 const (
@@ -29,13 +29,18 @@ const (
 )
 
 // SNMPIfaces registers a SNMP Interfaces collector for the given community and host.
-func SNMPIfaces(community, host string) {
+func SNMPIfaces(community util.Secret, host string) {
 	collectors = append(collectors, &IntervalCollector{
 		F: func() (datapoint.MultiDataPoint, error) {
-			return c_snmp_ifaces(community, host)
+			c, err := community.Value()
+			if err != nil {
+				return nil, err
+			}
+			return c_snmp_ifaces(c, host)
 		},
 		Interval: time.Second * 30,
 		name:     fmt.Sprintf("snmp-ifaces-%s", host),
+		remote:   true,
 	})
 }
 
@@ -72,39 +77,39 @@ func c_snmp_ifaces(community, host string) (datapoint.MultiDataPoint, error) {
 		}
 	}
 	var md datapoint.MultiDataPoint
-	add := func(oid, metric, dir string) error {
+	add := func(oid, metric, dir string, unit metadata.Unit) error {
 		m, err := snmp_subtree(host, community, oid)
 		if err != nil {
 			return err
 		}
 		for k, v := range m {
 			tags := datapoint.TagSet{
-				"host":      host,
+				"host":      snmpHostTag(host),
 				"direction": dir,
 				"iface":     fmt.Sprintf("%d", k),
 				"iname":     names[k],
 			}
-			Add(&md, switch_bond(metric, names[k]), v, tags, metadata.Unknown, metadata.None, "")
+			Add(&md, switch_bond(metric, names[k]), v, tags, metadata.Counter, unit, "")
 			metadata.AddMeta("", tags, "alias", aliases[k], false)
 		}
 		return nil
 	}
 	oids := []snmpAdd{
-		{ifHCInBroadcastPkts, osNetBroadcast, "in"},
-		{ifHCInMulticastPkts, osNetMulticast, "in"},
-		{ifHCInUcastPkts, osNetUnicast, "in"},
-		{ifHCOutBroadcastPkts, osNetBroadcast, "out"},
-		{ifHCOutMulticastPkts, osNetMulticast, "out"},
-		{ifHCOutOctets, osNetBytes, "out"},
-		{ifHCOutUcastPkts, osNetUnicast, "out"},
-		{ifHCinOctets, osNetBytes, "in"},
-		{ifInDiscards, osNetDropped, "in"},
-		{ifInErrors, osNetErrors, "in"},
-		{ifOutDiscards, osNetDropped, "out"},
-		{ifOutErrors, osNetErrors, "out"},
+		{ifHCInBroadcastPkts, osNetBroadcast, "in", metadata.Count},
+		{ifHCInMulticastPkts, osNetMulticast, "in", metadata.Count},
+		{ifHCInUcastPkts, osNetUnicast, "in", metadata.Count},
+		{ifHCOutBroadcastPkts, osNetBroadcast, "out", metadata.Count},
+		{ifHCOutMulticastPkts, osNetMulticast, "out", metadata.Count},
+		{ifHCOutOctets, osNetBytes, "out", metadata.Bytes},
+		{ifHCOutUcastPkts, osNetUnicast, "out", metadata.Count},
+		{ifHCinOctets, osNetBytes, "in", metadata.Bytes},
+		{ifInDiscards, osNetDropped, "in", metadata.Count},
+		{ifInErrors, osNetErrors, "in", metadata.Count},
+		{ifOutDiscards, osNetDropped, "out", metadata.Count},
+		{ifOutErrors, osNetErrors, "out", metadata.Count},
 	}
 	for _, o := range oids {
-		if err := add(o.oid, o.metric, o.dir); err != nil {
+		if err := add(o.oid, o.metric, o.dir, o.unit); err != nil {
 			return nil, err
 		}
 	}
@@ -115,4 +120,5 @@ type snmpAdd struct {
 	oid    string
 	metric string
 	dir    string
+	unit   metadata.Unit
 }
@@ -0,0 +1,195 @@
+package collectors
+
+import (
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestSNMPSubtree(t *testing.T) {
+	fx := loadFixture(t, "snmp_ifaces")
+	names, err := snmp_subtree(fx.SNMP.Addr(), "public", ifName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id, name := range map[int]string{1: "GigabitEthernet0/1", 2: "GigabitEthernet0/2", 3: "port-channel1", 10: "Vlan1"} {
+		if got := fmt.Sprintf("%s", names[id]); got != name {
+			t.Errorf("%d: got %q, expected %q", id, got, name)
+		}
+	}
+	octets, err := snmp_subtree(fx.SNMP.Addr(), "public", ifHCinOctets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := octets[3].(*big.Int); !ok || v.String() != "18446744073709551000" {
+		t.Errorf("Counter64 over 2^63: got %v", octets[3])
+	}
+}
+
+// TestSNMPSubtreeBulk walks a table longer than a GETBULK response. As
+// snmp.Walk fails on endOfMibView instead of ending the walk, the table is
+// followed by more variables than a response holds.
+func TestSNMPSubtreeBulk(t *testing.T) {
+	var dump strings.Builder
+	for i := 1; i <= 40; i++ {
+		fmt.Fprintf(&dump, ".1.3.6.1.2.1.2.2.1.14.%d = Counter32: %d\n", i, i*10)
+		fmt.Fprintf(&dump, ".1.3.6.1.2.1.2.2.1.19.%d = Counter32: 0\n", i)
+	}
+	vars, err := parseSNMPWalk(strings.NewReader(dump.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := startSNMPAgent(t, "public", vars)
+	m, err := snmp_subtree(a.Addr(), "public", ifInErrors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 40 || fmt.Sprint(m[40]) != "400" {
+		t.Errorf("got %d rows: %v", len(m), m)
+	}
+}
+
+func TestSNMPOid(t *testing.T) {
+	fx := loadFixture(t, "snmp_cisco")
+	if v, err := snmp_oid(fx.SNMP.Addr(), "public", ciscoCPU+".1"); err != nil || v.String() != "7" {
+		t.Errorf("got %v, %v", v, err)
+	}
+	if _, err := snmp_oid(fx.SNMP.Addr(), "public", ciscoCPU); err == nil || !strings.Contains(err.Error(), "no such instance") {
+		t.Errorf("got %v, expected no such instance", err)
+	}
+	if _, err := snmp_oid(fx.SNMP.Addr(), "public", ".1.3.6.1.4.1.2636.3.1.13.1.8.9.1.0.0"); err == nil || !strings.Contains(err.Error(), "no such object") {
+		t.Errorf("got %v, expected no such object", err)
+	}
+}
+
+func TestSNMPWrongCommunity(t *testing.T) {
+	fx := loadFixture(t, "snmp_cisco")
+	if _, err := snmp_oid(fx.SNMP.Addr(), "private", ciscoCPU+".1"); err == nil || !strings.Contains(err.Error(), "authorization error") {
+		t.Errorf("got %v, expected authorization error", err)
+	}
+	if _, err := c_snmp_cisco("private", fx.SNMP.Addr()); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestSNMPCommunitySecret(t *testing.T) {
+	fx := loadFixture(t, "snmp_cisco")
+	t.Setenv("SNMP_COMMUNITY", "public")
+	prev := collectors
+	t.Cleanup(func() { collectors = prev })
+	SNMPCisco("env:SNMP_COMMUNITY", fx.SNMP.Addr())
+	SNMPCisco("public", fx.SNMP.Addr())
+	SNMPCisco("env:SNMP_MISSING", fx.SNMP.Addr())
+	cs := collectors[len(prev):]
+	for _, c := range cs {
+		if strings.Contains(c.Name(), "public") {
+			t.Errorf("community in name %s", c.Name())
+		}
+	}
+	if _, err := cs[0].(*IntervalCollector).F(); err != nil {
+		t.Errorf("env: %v", err)
+	}
+	if _, err := cs[1].(*IntervalCollector).F(); err != nil {
+		t.Errorf("plain: %v", err)
+	}
+	if _, err := cs[2].(*IntervalCollector).F(); err == nil || !strings.Contains(err.Error(), "env:SNMP_MISSING") {
+		t.Errorf("missing: got %v", err)
+	}
+}
+
+func TestSNMPTimeout(t *testing.T) {
+	if testing.Short() {
+		t.Skip("waits for the 5s timeout of SNMP requests")
+	}
+	fx := loadFixture(t, "snmp_ifaces")
+	fx.SNMP.drop.Store(true)
+	_, err := snmp_subtree(fx.SNMP.Addr(), "public", ifName)
+	if err == nil || !strings.Contains(err.Error(), "timeout") {
+		t.Errorf("got %v, expected a timeout", err)
+	}
+}
+
+// TestSNMPAgentV1 checks the errors of v1, which has no exceptions in
+// bindings.
+func TestSNMPAgentV1(t *testing.T) {
+	fx := loadFixture(t, "snmp_cisco")
+	request := func(tag int, oid asn1.ObjectIdentifier) (p snmpPDU) {
+		pdu, err := asn1.Marshal(snmpPDU{RequestID: 1, Bindings: []snmpBinding{{oid, snmpNull}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		pdu[0] = 0xa0 | byte(tag)
+		msg, err := asn1.Marshal(snmpMessage{Version: 0, Community: []byte("public"), PDU: asn1.RawValue{FullBytes: pdu}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		b := fx.SNMP.handle(msg)
+		if b == nil {
+			return
+		}
+		var resp snmpResponse
+		if _, err := asn1.Unmarshal(b, &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp.PDU
+	}
+	sysName := asn1.ObjectIdentifier{1, 3, 6, 1, 2, 1, 1, 5, 0}
+	if p := request(0, sysName); p.ErrorStatus != 0 || len(p.Bindings) != 1 {
+		t.Errorf("get: got %+v", p)
+	}
+	if p := request(0, append(sysName, 1)); p.ErrorStatus != snmpNoSuchName || p.ErrorIndex != 1 {
+		t.Errorf("get missing: got %+v", p)
+	}
+	if p := request(1, asn1.ObjectIdentifier{1, 3, 6, 1, 6, 3, 18, 1, 1, 1, 8, 112, 117, 98, 108, 105, 99}); p.ErrorStatus != snmpNoSuchName {
+		t.Errorf("getnext at the end: got %+v", p)
+	}
+	if p := request(5, sysName); p.RequestID != 0 {
+		t.Errorf("getbulk: got %+v, expected no response", p)
+	}
+}
+
+func TestParseSNMPWalk(t *testing.T) {
+	vars, err := parseSNMPWalk(strings.NewReader(`
+# comment
+.1.3.6.1.2.1.2.2.1.8.1 = INTEGER: up(1)
+.1.3.6.1.2.1.1.3.0 = Timeticks: (81203340) 9 days, 9:33:53.40
+.1.3.6.1.2.1.4.20.1.1.10.0.0.1 = IpAddress: 10.0.0.1
+.1.3.6.1.2.1.31.1.1.1.18.2 = ""
+.1.3.6.1.2.1.31.1.1.1.6.1 = Counter64: 18446744073709551615
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"1.3.6.1.2.1.1.3.0 430404d7108c",
+		"1.3.6.1.2.1.2.2.1.8.1 020101",
+		"1.3.6.1.2.1.4.20.1.1.10.0.0.1 40040a000001",
+		"1.3.6.1.2.1.31.1.1.1.6.1 460900ffffffffffffffff",
+		"1.3.6.1.2.1.31.1.1.1.18.2 0400",
+	}
+	if len(vars) != len(expected) {
+		t.Fatalf("got %d variables", len(vars))
+	}
+	for i, v := range vars {
+		if got := fmt.Sprintf("%v %x", v.oid, v.value.FullBytes); got != expected[i] {
+			t.Errorf("got %s, expected %s", got, expected[i])
+		}
+	}
+	if _, err := parseSNMPWalk(strings.NewReader(".1.3.6.1.2.1.1.1.0 = Opaque: 00\n")); err == nil {
+		t.Error("expected an error for an unsupported type")
+	}
+}
+
+func Test_c_snmp_ifaces_golden(t *testing.T) {
+	fx := loadFixture(t, "snmp_ifaces")
+	md, err := c_snmp_ifaces("public", fx.SNMP.Addr())
+	fx.check(t, md, err)
+}
+
+func Test_c_snmp_cisco_golden(t *testing.T) {
+	fx := loadFixture(t, "snmp_cisco")
+	md, err := c_snmp_cisco("public", fx.SNMP.Addr())
+	fx.check(t, md, err)
+}
@@ -44,7 +44,7 @@ func init() {
 		F:        c_mssql_replica_votes,
 		Interval: time.Minute * 5,
 	}
-	c_replica_votes.init = wmiInitNamespace(c_replica_votes, func() interface{} { return &[]MSCluster_Node{} }, fmt.Sprintf("WHERE Name = '%s'", util.Hostname), &sqlAGVotes, rootMSCluster)
+	c_replica_votes.init = wmiInitNamespace(c_replica_votes, func() interface{} { return &[]MSCluster_Node{} }, fmt.Sprintf("WHERE Name = '%s'", util.Host()), &sqlAGVotes, rootMSCluster)
 	collectors = append(collectors, c_replica_votes)
 
 	c_replica_resources := &IntervalCollector{
@@ -477,7 +477,7 @@ type MSCluster_Cluster struct {
 func c_mssql_replica_resources() (datapoint.MultiDataPoint, error) {
 	var dst []MSCluster_Resource
 	//Only report metrics for resources owned by this node
-	var q = wmi.CreateQuery(&dst, fmt.Sprintf("WHERE OwnerNode = '%s'", util.Hostname))
+	var q = wmi.CreateQuery(&dst, fmt.Sprintf("WHERE OwnerNode = '%s'", util.Host()))
 	if err := queryWmiNamespace(q, &dst, rootMSCluster); err != nil {
 		return nil, err
 	}
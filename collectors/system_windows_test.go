@@ -2,6 +2,8 @@ package collectors
 
 import (
 	"testing"
+
+	"github.com/oliveagle/go-collectors/datapoint"
 )
 
 func Test_c_system_windows(t *testing.T) {
@@ -15,7 +17,7 @@ func Test_c_system_windows(t *testing.T) {
 	t.Log("isOk", isOk)
 	for idx := range md {
 		t.Log(md[idx])
-		if md[idx].Metric == "os.system.uptime" && md[idx].Value.(uint64) > 0 {
+		if md[idx].Metric == "os.system.uptime" && md[idx].Value.(datapoint.Value).Float64() > 0 {
 			isOk = true
 		}
 	}
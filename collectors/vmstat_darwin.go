@@ -6,7 +6,6 @@ import (
 
 	"github.com/oliveagle/go-collectors/datapoint"
 	"github.com/oliveagle/go-collectors/metadata"
-	"github.com/oliveagle/go-collectors/util"
 )
 
 func init() {
@@ -16,7 +15,7 @@ func init() {
 func c_vmstat_darwin() (datapoint.MultiDataPoint, error) {
 	var md datapoint.MultiDataPoint
 	var free float64
-	util.ReadCommand(func(line string) error {
+	readCommand(func(line string) error {
 		if line == "" || strings.HasPrefix(line, "Object cache") || strings.HasPrefix(line, "Mach Virtual") {
 			return nil
 		}
@@ -44,7 +43,7 @@ func c_vmstat_darwin() (datapoint.MultiDataPoint, error) {
 		}
 		return nil
 	}, "vm_stat")
-	util.ReadCommand(func(line string) error {
+	readCommand(func(line string) error {
 		total, _ := strconv.ParseFloat(line, 64)
 		if total == 0 {
 			return nil
@@ -14,12 +14,17 @@ import (
 )
 
 // Vsphere registers a vSphere collector.
-func Vsphere(user, pwd, host string) {
+func Vsphere(user string, pwd util.Secret, host string) {
 	collectors = append(collectors, &IntervalCollector{
 		F: func() (datapoint.MultiDataPoint, error) {
-			return c_vsphere(user, pwd, host)
+			p, err := pwd.Value()
+			if err != nil {
+				return nil, err
+			}
+			return c_vsphere(user, p, host)
 		},
-		name: fmt.Sprintf("vsphere-%s", host),
+		name:   fmt.Sprintf("vsphere-%s", host),
+		remote: true,
 	})
 }
 
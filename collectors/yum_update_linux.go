@@ -19,9 +19,9 @@ func yum_update_stats_linux() (datapoint.MultiDataPoint, error) {
 	kernel_c := 0
 	// This is a silly long timeout, but until we implement sigint this will
 	// Prevent a currupt yum db https://github.com/bosun-monitor/scollector/issues/56
-	err := util.ReadCommandTimeout(time.Minute*5, func(line string) error {
+	err := readCommandTimeout(time.Minute*5, func(line string) error {
 		fields := strings.Fields(line)
-		if len(fields) > 1 && !strings.HasPrefix(fields[0], "Updated Packages") {
+		if len(fields) > 1 && !strings.HasPrefix(line, "Updated Packages") {
 			if strings.HasPrefix(fields[0], "kern") {
 				kernel_c++
 			} else {
@@ -0,0 +1,20 @@
+package collectors
+
+import (
+	"testing"
+
+	"github.com/oliveagle/go-collectors/util"
+)
+
+func Test_yum_update_stats_linux_golden(t *testing.T) {
+	fx := loadFixture(t, "yum_update_stats_linux")
+	md, err := yum_update_stats_linux()
+	fx.check(t, md, err)
+}
+
+func Test_yum_update_stats_linux_missing(t *testing.T) {
+	setCommands(t, util.FixtureRunner{})
+	if md, err := yum_update_stats_linux(); md != nil || err != nil {
+		t.Errorf("got %v, %v", md, err)
+	}
+}
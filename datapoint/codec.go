@@ -0,0 +1,248 @@
+package datapoint
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// The binary encoding of a MultiDataPoint is:
+//
+//	magic     "MDP"
+//	version   byte, currently 1
+//	count     uvarint number of datapoints
+//	datapoint count times:
+//	  metric  string
+//	  seconds varint, delta to the seconds of the previous datapoint
+//	  nanos   uvarint
+//	  kind    byte, the Kind of the value
+//	  value   varint for Int, uvarint for Uint, 8 byte little endian
+//	          IEEE 754 bits for Float
+//	  tags    uvarint number of tags, followed by key and value strings
+//	          sorted by key; values are never empty
+//
+// Strings are deduplicated within a batch: a string is encoded as a uvarint
+// n. If n is 0 it is followed by the uvarint length and bytes of a new
+// string, which is appended to the string table; otherwise it refers to
+// entry n-1 of the table.
+const (
+	codecMagic   = "MDP"
+	codecVersion = 1
+)
+
+var errShortBuffer = errors.New("datapoint: decode: unexpected end of input")
+
+// MarshalBinary encodes m in the compact binary format. Values are converted
+// with ParseValue, so an error is returned for values that are not numbers.
+func (m MultiDataPoint) MarshalBinary() ([]byte, error) {
+	e := &encoder{
+		buf:     make([]byte, 0, 16+len(m)*24),
+		strings: make(map[string]uint64),
+	}
+	e.buf = append(e.buf, codecMagic...)
+	e.buf = append(e.buf, codecVersion)
+	e.uvarint(uint64(len(m)))
+	var prev int64
+	for _, d := range m {
+		v, err := ParseValue(d.Value)
+		if err != nil {
+			return nil, fmt.Errorf("datapoint: encode %s: %v", d.Metric, err)
+		}
+		e.string(d.Metric)
+		sec := d.Timestamp.Unix()
+		e.varint(sec - prev)
+		prev = sec
+		e.uvarint(uint64(d.Timestamp.Nanosecond()))
+		e.buf = append(e.buf, byte(v.kind))
+		switch v.kind {
+		case Int:
+			e.varint(v.i)
+		case Uint:
+			e.uvarint(v.u)
+		case Float:
+			e.buf = binary.LittleEndian.AppendUint64(e.buf, math.Float64bits(v.f))
+		}
+		e.uvarint(uint64(d.Tags.Len()))
+		d.Tags.Range(func(k, v string) bool {
+			e.string(k)
+			e.string(v)
+			return true
+		})
+	}
+	return e.buf, nil
+}
+
+type encoder struct {
+	buf     []byte
+	strings map[string]uint64
+}
+
+func (e *encoder) uvarint(u uint64) {
+	e.buf = binary.AppendUvarint(e.buf, u)
+}
+
+func (e *encoder) varint(i int64) {
+	e.buf = binary.AppendVarint(e.buf, i)
+}
+
+func (e *encoder) string(s string) {
+	if n, ok := e.strings[s]; ok {
+		e.uvarint(n)
+		return
+	}
+	e.strings[s] = uint64(len(e.strings) + 1)
+	e.uvarint(0)
+	e.uvarint(uint64(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+// UnmarshalBinary decodes datapoints encoded by MarshalBinary into m. The
+// values of the decoded datapoints are Values.
+func (m *MultiDataPoint) UnmarshalBinary(b []byte) error {
+	if len(b) < len(codecMagic)+1 || string(b[:len(codecMagic)]) != codecMagic {
+		return fmt.Errorf("datapoint: decode: bad magic")
+	}
+	if v := b[len(codecMagic)]; v != codecVersion {
+		return fmt.Errorf("datapoint: decode: unsupported version %d", v)
+	}
+	d := &decoder{buf: b[len(codecMagic)+1:]}
+	n := d.uvarint()
+	// Every datapoint takes at least 5 bytes, which bounds the allocation
+	// for corrupt input.
+	if d.err == nil && n > uint64(len(d.buf))/5 {
+		return errShortBuffer
+	}
+	md := make(MultiDataPoint, 0, n)
+	var sec int64
+	for i := uint64(0); i < n && d.err == nil; i++ {
+		dp := &DataPoint{Metric: d.string()}
+		sec += d.varint()
+		nsec := d.uvarint()
+		if d.err == nil && nsec >= uint64(time.Second) {
+			return fmt.Errorf("datapoint: decode: bad nanoseconds %d", nsec)
+		}
+		dp.Timestamp = time.Unix(sec, int64(nsec))
+		dp.Value = d.value()
+		if nt := d.uvarint(); nt > 0 && d.err == nil {
+			if nt > uint64(len(d.buf))/2 {
+				return errShortBuffer
+			}
+			pairs := make([]string, 0, 2*nt)
+			for j := uint64(0); j < nt && d.err == nil; j++ {
+				k, v := d.string(), d.string()
+				if d.err != nil {
+					break
+				}
+				if v == "" || j > 0 && k <= pairs[len(pairs)-2] {
+					return fmt.Errorf("datapoint: decode: bad tags in %s", dp.Metric)
+				}
+				pairs = append(pairs, Intern(k), Intern(v))
+			}
+			dp.Tags = makeTags(pairs)
+		}
+		md = append(md, dp)
+	}
+	if d.err != nil {
+		return d.err
+	}
+	if len(d.buf) != 0 {
+		return fmt.Errorf("datapoint: decode: %d trailing bytes", len(d.buf))
+	}
+	*m = md
+	return nil
+}
+
+type decoder struct {
+	buf     []byte
+	strings []string
+	err     error
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	u, n := binary.Uvarint(d.buf)
+	if n <= 0 {
+		d.err = errShortBuffer
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return u
+}
+
+func (d *decoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	i, n := binary.Varint(d.buf)
+	if n <= 0 {
+		d.err = errShortBuffer
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return i
+}
+
+func (d *decoder) string() string {
+	ref := d.uvarint()
+	if d.err != nil {
+		return ""
+	}
+	if ref > 0 {
+		if ref > uint64(len(d.strings)) {
+			d.err = fmt.Errorf("datapoint: decode: bad string reference %d", ref)
+			return ""
+		}
+		return d.strings[ref-1]
+	}
+	l := d.uvarint()
+	if d.err != nil {
+		return ""
+	}
+	if l > uint64(len(d.buf)) {
+		d.err = errShortBuffer
+		return ""
+	}
+	s := string(d.buf[:l])
+	d.buf = d.buf[l:]
+	d.strings = append(d.strings, s)
+	return s
+}
+
+func (d *decoder) value() Value {
+	if d.err != nil {
+		return Value{}
+	}
+	if len(d.buf) == 0 {
+		d.err = errShortBuffer
+		return Value{}
+	}
+	k := Kind(d.buf[0])
+	d.buf = d.buf[1:]
+	switch k {
+	case Int:
+		return IntValue(d.varint())
+	case Uint:
+		u := d.uvarint()
+		if d.err == nil && u <= math.MaxInt64 {
+			d.err = fmt.Errorf("datapoint: decode: non-canonical uint %d", u)
+		}
+		return Value{kind: Uint, u: u}
+	case Float:
+		if len(d.buf) < 8 {
+			d.err = errShortBuffer
+			return Value{}
+		}
+		v, err := FloatValue(math.Float64frombits(binary.LittleEndian.Uint64(d.buf)))
+		d.buf = d.buf[8:]
+		if err != nil {
+			d.err = fmt.Errorf("datapoint: decode: %v", err)
+		}
+		return v
+	}
+	d.err = fmt.Errorf("datapoint: decode: bad value kind %d", k)
+	return Value{}
+}
@@ -0,0 +1,174 @@
+package datapoint
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+func equalMultiDataPoint(a, b MultiDataPoint) error {
+	if len(a) != len(b) {
+		return fmt.Errorf("length %d != %d", len(a), len(b))
+	}
+	for i := range a {
+		av, err := a[i].Number()
+		if err != nil {
+			return err
+		}
+		bv, err := b[i].Number()
+		if err != nil {
+			return err
+		}
+		if a[i].Metric != b[i].Metric || !a[i].Timestamp.Equal(b[i].Timestamp) || av != bv || !a[i].Tags.Equal(b[i].Tags) {
+			return fmt.Errorf("%d: %v != %v", i, a[i], b[i])
+		}
+	}
+	return nil
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	now := time.Now()
+	md := MultiDataPoint{
+		{Metric: "linux.cpu", Timestamp: now, Value: 1, Tags: NewTags(TagSet{"host": "web01", "type": "user"})},
+		{Metric: "linux.cpu", Timestamp: now, Value: int64(-5), Tags: NewTags(TagSet{"host": "web01", "type": "system"})},
+		{Metric: "linux.mem.free", Timestamp: now.Add(-time.Hour), Value: uint64(math.MaxUint64), Tags: NewTags(TagSet{"host": "web01"})},
+		{Metric: "linux.loadavg_1_min", Timestamp: now.Add(time.Minute), Value: 0.25},
+		{Metric: "old", Timestamp: time.Unix(0, 0), Value: "42", Tags: NewTags(TagSet{})},
+		{Metric: "zero", Value: 1.5e300},
+	}
+	b, err := md.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got MultiDataPoint
+	if err := got.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := equalMultiDataPoint(md, got); err != nil {
+		t.Fatal(err)
+	}
+	if !got[5].Timestamp.IsZero() {
+		t.Errorf("expected zero timestamp, got %v", got[5].Timestamp)
+	}
+	if v := got[2].Value.(Value); v.Kind() != Uint {
+		t.Errorf("expected Uint, got %v", v.Kind())
+	}
+	// Repeated strings are only stored once.
+	if n := bytes.Count(b, []byte("web01")); n != 1 {
+		t.Errorf("web01 stored %d times", n)
+	}
+	if _, err := (MultiDataPoint{{Metric: "bad", Value: "x"}}).MarshalBinary(); err == nil {
+		t.Error("expected error for bad value")
+	}
+}
+
+func TestCodecErrors(t *testing.T) {
+	b, err := testMultiDataPoint(3).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var md MultiDataPoint
+	for i := 0; i < len(b); i++ {
+		if err := md.UnmarshalBinary(b[:i]); err == nil {
+			t.Errorf("expected error for truncated input of %d bytes", i)
+		}
+	}
+	if err := md.UnmarshalBinary(append(b, 0)); err == nil {
+		t.Error("expected error for trailing bytes")
+	}
+	v := append([]byte{}, b...)
+	v[3] = 2
+	if err := md.UnmarshalBinary(v); err == nil {
+		t.Error("expected error for unknown version")
+	}
+	// A reference to a string that was not defined.
+	if err := md.UnmarshalBinary([]byte("MDP\x01\x01\x05\x00\x00\x01\x00\x00")); err == nil {
+		t.Error("expected error for bad string reference")
+	}
+}
+
+func testMultiDataPoint(n int) MultiDataPoint {
+	now := time.Now()
+	md := make(MultiDataPoint, n)
+	for i := range md {
+		md[i] = &DataPoint{
+			Metric:    fmt.Sprintf("linux.disk.%d", i%10),
+			Timestamp: now,
+			Value:     IntValue(int64(i) * 1024),
+			Tags:      NewTags(TagSet{"host": "web01", "dev": fmt.Sprintf("sd%c", 'a'+i%4)}),
+		}
+	}
+	return md
+}
+
+func FuzzUnmarshalBinary(f *testing.F) {
+	for _, n := range []int{0, 1, 5} {
+		b, err := testMultiDataPoint(n).MarshalBinary()
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(b)
+	}
+	f.Fuzz(func(t *testing.T, b []byte) {
+		var md MultiDataPoint
+		if err := md.UnmarshalBinary(b); err != nil {
+			return
+		}
+		e, err := md.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got MultiDataPoint
+		if err := got.UnmarshalBinary(e); err != nil {
+			t.Fatal(err)
+		}
+		if err := equalMultiDataPoint(md, got); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func BenchmarkMarshalBinary(b *testing.B) {
+	md := testMultiDataPoint(100)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf, _ := md.MarshalBinary()
+		b.SetBytes(int64(len(buf)))
+	}
+}
+
+func BenchmarkUnmarshalBinary(b *testing.B) {
+	buf, _ := testMultiDataPoint(100).MarshalBinary()
+	b.SetBytes(int64(len(buf)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var md MultiDataPoint
+		if err := md.UnmarshalBinary(buf); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMarshalJSON(b *testing.B) {
+	md := testMultiDataPoint(100)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf, _ := json.Marshal(md)
+		b.SetBytes(int64(len(buf)))
+	}
+}
+
+func BenchmarkUnmarshalJSON(b *testing.B) {
+	buf, _ := json.Marshal(testMultiDataPoint(100))
+	b.SetBytes(int64(len(buf)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var md MultiDataPoint
+		if err := json.Unmarshal(buf, &md); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
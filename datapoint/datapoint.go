@@ -3,25 +3,52 @@ package datapoint
 import (
 	"encoding/json"
 	"fmt"
-	"math"
-	"math/big"
-	"strconv"
 	"time"
 )
 
-var bigMaxInt64 = big.NewInt(math.MaxInt64)
-
 // DataPoint is a data point for the /api/put route:
 // http://opentsdb.net/docs/build/html/api_http/put.html#example-single-data-point-put.
 type DataPoint struct {
 	Metric string `json:"metric"`
 	// Timestamp int64       `json:"timestamp"`
-	Timestamp time.Time   `json:"timestamp"`
-	Value     interface{} `json:"value"`
-	Tags      TagSet      `json:"tags"`
+	Timestamp time.Time `json:"timestamp"`
+	// Value is a Value for datapoints created with NewDataPoint. Other
+	// numeric types and numeric strings are still accepted and converted
+	// when marshaling; use Number to read any of them as a Value.
+	Value interface{} `json:"value"`
+	Tags  Tags        `json:"tags"`
 }
 
-// MarshalJSON verifies d is valid and converts it to JSON.
+// NewDataPoint returns a DataPoint with value parsed into a Value. The
+// metric and tags are not sanitized, since that depends on the NamingPolicy
+// of the sink they are sent to, but an error is returned if the value
+// cannot be parsed or the metric or a tag key is empty.
+func NewDataPoint(metric string, ts time.Time, value interface{}, tags Tags) (*DataPoint, error) {
+	v, err := ParseValue(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", metric, err)
+	}
+	if metric == "" {
+		return nil, fmt.Errorf("empty metric")
+	}
+	if _, ok := tags.Get(""); ok {
+		return nil, fmt.Errorf("%s: empty tag key", metric)
+	}
+	return &DataPoint{
+		Metric:    metric,
+		Timestamp: ts,
+		Value:     v,
+		Tags:      tags,
+	}, nil
+}
+
+// Number returns the value of d as a Value.
+func (d *DataPoint) Number() (Value, error) {
+	return ParseValue(d.Value)
+}
+
+// MarshalJSON verifies d is valid and converts it to JSON for OpenTSDB. The
+// metric and tags are cleaned with the OpenTSDB NamingPolicy.
 func (d *DataPoint) MarshalJSON() ([]byte, error) {
 	if err := d.clean(); err != nil {
 		return nil, err
@@ -30,7 +57,7 @@ func (d *DataPoint) MarshalJSON() ([]byte, error) {
 		Metric    string      `json:"metric"`
 		Timestamp time.Time   `json:"timestamp"`
 		Value     interface{} `json:"value"`
-		Tags      TagSet      `json:"tags"`
+		Tags      Tags        `json:"tags"`
 	}{
 		d.Metric,
 		d.Timestamp,
@@ -40,9 +67,11 @@ func (d *DataPoint) MarshalJSON() ([]byte, error) {
 }
 
 func (d *DataPoint) clean() error {
-	if err := d.Tags.Clean(); err != nil {
+	tags, err := d.Tags.Clean()
+	if err != nil {
 		return err
 	}
+	d.Tags = tags
 	m, err := Clean(d.Metric)
 	if err != nil {
 		return fmt.Errorf("cleaning metric %s: %s", d.Metric, err)
@@ -50,26 +79,11 @@ func (d *DataPoint) clean() error {
 	if d.Metric != m {
 		d.Metric = m
 	}
-	switch v := d.Value.(type) {
-	case string:
-		if i, err := strconv.ParseInt(v, 10, 64); err == nil {
-			d.Value = i
-		} else if f, err := strconv.ParseFloat(v, 64); err == nil {
-			d.Value = f
-		} else {
-			return fmt.Errorf("Unparseable number %v", v)
-		}
-	case uint64:
-		if v > math.MaxInt64 {
-			d.Value = float64(v)
-		}
-	case *big.Int:
-		if bigMaxInt64.Cmp(v) < 0 {
-			if f, err := strconv.ParseFloat(v.String(), 64); err == nil {
-				d.Value = f
-			}
-		}
+	v, err := ParseValue(d.Value)
+	if err != nil {
+		return fmt.Errorf("%s: %v", d.Metric, err)
 	}
+	d.Value = v
 	return nil
 }
 
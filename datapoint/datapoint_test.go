@@ -9,7 +9,7 @@ import (
 func Test_MarshalDataPoint(t *testing.T) {
 	d := DataPoint{
 		Metric:    "metric1",
-		Timestamp: time.Now().UnixNano(),
+		Timestamp: time.Now(),
 		Value:     1,
 	}
 
@@ -30,12 +30,12 @@ func Test_UnmarshalDataPoint(t *testing.T) {
 func Test_MarshalDataPoints(t *testing.T) {
 	d1 := DataPoint{
 		Metric:    "metric1",
-		Timestamp: time.Now().UnixNano(),
+		Timestamp: time.Now(),
 		Value:     1,
 	}
 	d2 := DataPoint{
 		Metric:    "metric2",
-		Timestamp: time.Now().UnixNano(),
+		Timestamp: time.Now(),
 		Value:     2,
 	}
 	md := MultiDataPoint{&d1, &d2}
@@ -0,0 +1,263 @@
+package datapoint
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// TagFilter is a compiled filter over TagSets and Tags. It is parsed from OpenTSDB
+// style k=v pairs, where each value is one of:
+//
+//	web01                   exact value
+//	*, web*, *.example.com  wildcard, * matches any (possibly empty) string
+//	web01|web02             alternation of exact or wildcard values
+//	!web01, !web*|db*       negation of any of the above
+//	literal_or(a|b)         OpenTSDB's literal_or, not_literal_or,
+//	not_literal_or(a|b)     wildcard and regexp filter functions
+//	wildcard(web*)
+//	regexp(web\d+)
+//
+// A tag key referenced by a non-negated filter must be present in the
+// TagSet. A negated filter also matches if the key is absent.
+type TagFilter struct {
+	matchers []*tagMatcher
+}
+
+type tagMatcher struct {
+	key    string
+	value  string
+	negate bool
+	// The value is matched by re if set, otherwise by literals and globs.
+	literals map[string]bool
+	globs    []glob
+	re       *regexp.Regexp
+}
+
+// glob is a wildcard pattern split at its *s.
+type glob []string
+
+func newGlob(s string) glob {
+	return glob(strings.Split(s, "*"))
+}
+
+func (g glob) match(s string) bool {
+	if len(g) == 1 {
+		return s == g[0]
+	}
+	if !strings.HasPrefix(s, g[0]) {
+		return false
+	}
+	s = s[len(g[0]):]
+	last := g[len(g)-1]
+	for _, p := range g[1 : len(g)-1] {
+		i := strings.Index(s, p)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(p):]
+	}
+	return len(s) >= len(last) && strings.HasSuffix(s, last)
+}
+
+// ParseTagFilter parses a filter of the form k=v,m=o.
+func ParseTagFilter(s string) (*TagFilter, error) {
+	f := &TagFilter{}
+	if strings.TrimSpace(s) == "" {
+		return f, nil
+	}
+	seen := make(map[string]bool)
+	for _, pair := range splitFilter(s) {
+		sp := strings.SplitN(pair, "=", 2)
+		if len(sp) != 2 {
+			return nil, fmt.Errorf("tag filter: bad tag: %s", pair)
+		}
+		k, v := strings.TrimSpace(sp[0]), strings.TrimSpace(sp[1])
+		if !ValidTag(k) {
+			return nil, fmt.Errorf("tag filter: invalid character in %s", k)
+		}
+		if seen[k] {
+			return nil, fmt.Errorf("tag filter: duplicated tag: %s", k)
+		}
+		seen[k] = true
+		m, err := compileTagMatcher(k, v)
+		if err != nil {
+			return nil, err
+		}
+		f.matchers = append(f.matchers, m)
+	}
+	sort.Sort(byKey(f.matchers))
+	return f, nil
+}
+
+// MustParseTagFilter is like ParseTagFilter but panics on error.
+func MustParseTagFilter(s string) *TagFilter {
+	f, err := ParseTagFilter(s)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+// NewTagFilter compiles a filter from t, for example as returned by
+// ParseTags.
+func NewTagFilter(t TagSet) (*TagFilter, error) {
+	f := &TagFilter{}
+	for k, v := range t {
+		m, err := compileTagMatcher(k, v)
+		if err != nil {
+			return nil, err
+		}
+		f.matchers = append(f.matchers, m)
+	}
+	sort.Sort(byKey(f.matchers))
+	return f, nil
+}
+
+// splitFilter splits s at commas outside of parentheses.
+func splitFilter(s string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			if depth > 0 {
+				depth--
+			}
+		case ',':
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+func compileTagMatcher(k, v string) (*tagMatcher, error) {
+	m := &tagMatcher{key: k, value: v}
+	if v == "" {
+		return nil, fmt.Errorf("tag filter: empty value for %s", k)
+	}
+	fn, arg := "", v
+	if i := strings.IndexByte(v, '('); i > 0 && strings.HasSuffix(v, ")") {
+		fn, arg = v[:i], v[i+1:len(v)-1]
+	}
+	switch fn {
+	case "":
+		if strings.HasPrefix(v, "!") {
+			m.negate = true
+			arg = v[1:]
+		}
+		return m, m.alternation(arg, true)
+	case "literal_or":
+		return m, m.alternation(arg, false)
+	case "not_literal_or":
+		m.negate = true
+		return m, m.alternation(arg, false)
+	case "wildcard":
+		m.globs = []glob{newGlob(arg)}
+		return m, nil
+	case "regexp":
+		re, err := regexp.Compile(arg)
+		if err != nil {
+			return nil, fmt.Errorf("tag filter: %s: %v", k, err)
+		}
+		m.re = re
+		return m, nil
+	}
+	return nil, fmt.Errorf("tag filter: unknown filter function %s", fn)
+}
+
+// alternation sets m to match any of the |-separated values of s, which may
+// contain wildcards if wildcards is true.
+func (m *tagMatcher) alternation(s string, wildcards bool) error {
+	for _, a := range strings.Split(s, "|") {
+		a = strings.TrimSpace(a)
+		if a == "" {
+			return fmt.Errorf("tag filter: empty value for %s", m.key)
+		}
+		if wildcards && strings.Contains(a, "*") {
+			m.globs = append(m.globs, newGlob(a))
+			continue
+		}
+		if m.literals == nil {
+			m.literals = make(map[string]bool)
+		}
+		m.literals[a] = true
+	}
+	return nil
+}
+
+func (m *tagMatcher) match(t TagGetter) bool {
+	v, ok := t.Get(m.key)
+	if !ok {
+		return m.negate
+	}
+	var r bool
+	switch {
+	case m.re != nil:
+		r = m.re.MatchString(v)
+	case m.literals[v]:
+		r = true
+	default:
+		for _, g := range m.globs {
+			if g.match(v) {
+				r = true
+				break
+			}
+		}
+	}
+	return r != m.negate
+}
+
+// Match reports whether t matches all filters of f. A nil or empty filter
+// matches everything.
+func (f *TagFilter) Match(t TagGetter) bool {
+	if f == nil {
+		return true
+	}
+	for _, m := range f.matchers {
+		if !m.match(t) {
+			return false
+		}
+	}
+	return true
+}
+
+// Keys returns the sorted tag keys f filters on.
+func (f *TagFilter) Keys() []string {
+	if f == nil {
+		return nil
+	}
+	keys := make([]string, len(f.matchers))
+	for i, m := range f.matchers {
+		keys[i] = m.key
+	}
+	return keys
+}
+
+// String returns f in the form it was parsed from, sorted by key.
+func (f *TagFilter) String() string {
+	if f == nil {
+		return ""
+	}
+	b := &bytes.Buffer{}
+	for i, m := range f.matchers {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(b, "%s=%s", m.key, m.value)
+	}
+	return b.String()
+}
+
+type byKey []*tagMatcher
+
+func (b byKey) Len() int           { return len(b) }
+func (b byKey) Less(i, j int) bool { return b[i].key < b[j].key }
+func (b byKey) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
@@ -0,0 +1,117 @@
+package datapoint
+
+import (
+	"testing"
+)
+
+func TestTagFilter(t *testing.T) {
+	web := TagSet{"host": "web01", "dc": "ny", "iface": "eth0"}
+	db := TagSet{"host": "db01.example.com", "dc": "la"}
+	tests := []struct {
+		filter  string
+		web, db bool
+	}{
+		{"", true, true},
+		{"host=web01", true, false},
+		{"host=*", true, true},
+		{"iface=*", true, false},
+		{"host=web*", true, false},
+		{"host=*.example.com", false, true},
+		{"host=d*01*com", false, true},
+		{"host=*0*", true, true},
+		{"host=web01|db02", true, false},
+		{"host=web02|db*", false, true},
+		{"host=!web01", false, true},
+		{"host=!web*|db*", false, false},
+		{"iface=!eth0", false, true},
+		{"iface=!*", false, true},
+		{"host=web01,dc=ny", true, false},
+		{"host=web01,dc=la", false, false},
+		{"host=literal_or(web01|db01)", true, false},
+		{"host=literal_or(web*)", false, false},
+		{"host=not_literal_or(web01)", false, true},
+		{"host=wildcard(*example*)", false, true},
+		{"host=regexp(^(web|db)\\d+)", true, true},
+		{"host=regexp(a{1,2}|web),dc=ny", true, false},
+	}
+	for _, test := range tests {
+		f, err := ParseTagFilter(test.filter)
+		if err != nil {
+			t.Errorf("%s: %v", test.filter, err)
+			continue
+		}
+		if m := f.Match(web); m != test.web {
+			t.Errorf("%s: web: got %v", test.filter, m)
+		}
+		if m := f.Match(db); m != test.db {
+			t.Errorf("%s: db: got %v", test.filter, m)
+		}
+	}
+}
+
+func TestTagFilterErrors(t *testing.T) {
+	for _, s := range []string{
+		"host",
+		"host=",
+		"host=a,host=b",
+		"ho st=a",
+		"host=a||b",
+		"host=regexp(()",
+		"host=median(a)",
+	} {
+		if _, err := ParseTagFilter(s); err == nil {
+			t.Errorf("%s: expected error", s)
+		}
+	}
+}
+
+func TestNewTagFilter(t *testing.T) {
+	ts, err := ParseTags("host=*,type=user|system")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := NewTagFilter(ts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.String() != "host=*,type=user|system" {
+		t.Errorf("unexpected string %s", f)
+	}
+	if !f.Match(TagSet{"host": "a", "type": "user"}) || f.Match(TagSet{"host": "a", "type": "idle"}) {
+		t.Error("unexpected match result")
+	}
+	if keys := f.Keys(); len(keys) != 2 || keys[0] != "host" {
+		t.Errorf("unexpected keys %v", keys)
+	}
+}
+
+func BenchmarkTagFilterMatch(b *testing.B) {
+	f := MustParseTagFilter("host=web*|db*,type=!idle,cpu=*")
+	t := TagSet{"host": "web01", "type": "user", "cpu": "3"}
+	for i := 0; i < b.N; i++ {
+		f.Match(t)
+	}
+}
+
+func FuzzParseTagFilter(f *testing.F) {
+	for _, s := range []string{"host=web01", "host=*.example.com,dc=!ny", "a=literal_or(b|c)", "a=regexp(x,y)", "a=b*c*d|!e"} {
+		f.Add(s, "web01.example.com")
+	}
+	f.Fuzz(func(t *testing.T, s, v string) {
+		tf, err := ParseTagFilter(s)
+		if err != nil {
+			return
+		}
+		ts := make(TagSet)
+		for _, k := range tf.Keys() {
+			ts[k] = v
+		}
+		again, err := ParseTagFilter(tf.String())
+		if err != nil {
+			t.Fatalf("%q: reparsing %q: %v", s, tf, err)
+		}
+		if tf.Match(ts) != again.Match(ts) {
+			t.Fatalf("%q: reparsed filter %q matches differently", s, tf)
+		}
+	})
+}
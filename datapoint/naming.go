@@ -0,0 +1,279 @@
+package datapoint
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// NamingPolicy sanitizes metric names and tags for a backend. Each method
+// returns the sanitized string, or an error if nothing valid is left.
+type NamingPolicy interface {
+	Name() string
+	Metric(s string) (string, error)
+	TagKey(s string) (string, error)
+	TagValue(s string) (string, error)
+}
+
+var (
+	// OpenTSDB allows letters, digits and -_./ and removes everything else.
+	// See: http://opentsdb.net/docs/build/html/user_guide/writing.html#metrics-and-tags
+	OpenTSDB NamingPolicy = openTSDBPolicy{}
+	// Prometheus maps metric names to [a-zA-Z_:][a-zA-Z0-9_:]* and label
+	// names to [a-zA-Z_][a-zA-Z0-9_]*, replacing invalid characters, such
+	// as '.', with '_'. Label values are left untouched.
+	Prometheus NamingPolicy = prometheusPolicy{}
+	// Graphite keeps '.' as the path separator in metric names and allows
+	// letters, digits and -_ in path components and tags, replacing
+	// everything else with '_'.
+	Graphite NamingPolicy = graphitePolicy{}
+	// Influx allows any printable unicode, since the line protocol escapes
+	// separators; only control characters are replaced with '_'.
+	Influx NamingPolicy = influxPolicy{}
+	// StatsD replaces the separators of the (Dog)StatsD protocol (:|@#,)
+	// and whitespace with '_'.
+	StatsD NamingPolicy = statsDPolicy{}
+)
+
+// mapRunes replaces all runes of s for which valid returns false with
+// replacement, collapsing consecutive invalid runes into one replacement.
+func mapRunes(s, replacement string, valid func(i int, r rune) bool) (string, error) {
+	var b bytes.Buffer
+	replaced := false
+	for i, r := range s {
+		if valid(i, r) {
+			b.WriteRune(r)
+			replaced = false
+		} else if !replaced {
+			b.WriteString(replacement)
+			replaced = true
+		}
+	}
+	if strings.Trim(b.String(), replacement) == "" {
+		return "", fmt.Errorf("sanitized result of %q is empty", s)
+	}
+	return b.String(), nil
+}
+
+type openTSDBPolicy struct{}
+
+func (openTSDBPolicy) Name() string                      { return "opentsdb" }
+func (openTSDBPolicy) Metric(s string) (string, error)   { return Clean(s) }
+func (openTSDBPolicy) TagKey(s string) (string, error)   { return Clean(s) }
+func (openTSDBPolicy) TagValue(s string) (string, error) { return Clean(s) }
+
+type prometheusPolicy struct{}
+
+func isASCIIAlnum(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9'
+}
+
+func (prometheusPolicy) Name() string { return "prometheus" }
+
+func (prometheusPolicy) Metric(s string) (string, error) {
+	if s != "" && s[0] >= '0' && s[0] <= '9' {
+		s = "_" + s
+	}
+	return mapRunes(s, "_", func(i int, r rune) bool {
+		return isASCIIAlnum(r) || r == '_' || r == ':'
+	})
+}
+
+func (prometheusPolicy) TagKey(s string) (string, error) {
+	if s != "" && s[0] >= '0' && s[0] <= '9' {
+		s = "_" + s
+	}
+	k, err := mapRunes(s, "_", func(i int, r rune) bool {
+		return isASCIIAlnum(r) || r == '_'
+	})
+	if err != nil {
+		return "", err
+	}
+	// Label names starting with __ are reserved for internal use.
+	if strings.HasPrefix(k, "__") {
+		k = "_" + strings.TrimLeft(k, "_")
+	}
+	return k, nil
+}
+
+func (prometheusPolicy) TagValue(s string) (string, error) {
+	if s == "" {
+		return "", fmt.Errorf("empty label value")
+	}
+	return s, nil
+}
+
+type graphitePolicy struct{}
+
+func (graphitePolicy) Name() string { return "graphite" }
+
+func (graphitePolicy) Metric(s string) (string, error) {
+	parts := strings.Split(strings.Trim(s, "."), ".")
+	for i, p := range parts {
+		c, err := graphitePolicy{}.TagValue(p)
+		if err != nil {
+			return "", fmt.Errorf("metric %q: %v", s, err)
+		}
+		parts[i] = c
+	}
+	return strings.Join(parts, "."), nil
+}
+
+func (graphitePolicy) TagKey(s string) (string, error) {
+	return graphitePolicy{}.TagValue(s)
+}
+
+func (graphitePolicy) TagValue(s string) (string, error) {
+	return mapRunes(s, "_", func(i int, r rune) bool {
+		return isASCIIAlnum(r) || r == '-' || r == '_'
+	})
+}
+
+type influxPolicy struct{}
+
+func (influxPolicy) Name() string { return "influx" }
+
+func (influxPolicy) Metric(s string) (string, error) { return influxPolicy{}.TagValue(s) }
+func (influxPolicy) TagKey(s string) (string, error) { return influxPolicy{}.TagValue(s) }
+
+func (influxPolicy) TagValue(s string) (string, error) {
+	return mapRunes(s, "_", func(i int, r rune) bool {
+		return !unicode.IsControl(r)
+	})
+}
+
+type statsDPolicy struct{}
+
+func (statsDPolicy) Name() string { return "statsd" }
+
+func (statsDPolicy) Metric(s string) (string, error) { return statsDPolicy{}.TagValue(s) }
+func (statsDPolicy) TagKey(s string) (string, error) { return statsDPolicy{}.TagValue(s) }
+
+func (statsDPolicy) TagValue(s string) (string, error) {
+	return mapRunes(s, "_", func(i int, r rune) bool {
+		return !unicode.IsSpace(r) && !unicode.IsControl(r) && !strings.ContainsRune(":|@#,", r)
+	})
+}
+
+// Sanitizer applies a NamingPolicy to datapoints for one sink. It remembers
+// which original series every sanitized series came from, so that distinct
+// series that become equal after sanitization, such as a.b and a_b for
+// Prometheus, are detected. Series not seen within Window, measured with
+// the datapoint timestamps, are forgotten.
+type Sanitizer struct {
+	Policy NamingPolicy
+	// Window is how long a series is remembered after it was last seen.
+	// Defaults to one hour.
+	Window time.Duration
+
+	sync.Mutex
+	origins   map[string]*origin
+	newest    time.Time
+	lastSweep time.Time
+}
+
+type origin struct {
+	series string
+	seen   time.Time
+}
+
+// NewSanitizer returns a Sanitizer for p.
+func NewSanitizer(p NamingPolicy) *Sanitizer {
+	return &Sanitizer{Policy: p, origins: make(map[string]*origin)}
+}
+
+func (s *Sanitizer) window() time.Duration {
+	if s.Window > 0 {
+		return s.Window
+	}
+	return time.Hour
+}
+
+// sweep forgets the series not seen within the window.
+func (s *Sanitizer) sweep() {
+	if s.newest.Sub(s.lastSweep) < s.window() {
+		return
+	}
+	s.lastSweep = s.newest
+	for key, o := range s.origins {
+		if s.newest.Sub(o.seen) > s.window() {
+			delete(s.origins, key)
+		}
+	}
+}
+
+// Sanitize returns sanitized copies of the datapoints of md; md itself is
+// not modified. Datapoints that cannot be sanitized, or that collide with a
+// different series sanitized earlier, are dropped and reported in errs.
+func (s *Sanitizer) Sanitize(md MultiDataPoint) (out MultiDataPoint, errs []error) {
+	s.Lock()
+	defer s.Unlock()
+	for _, d := range md {
+		c, err := s.sanitize(d)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s%s: %v", s.Policy.Name(), d.Metric, d.Tags, err))
+			continue
+		}
+		if d.Timestamp.After(s.newest) {
+			s.newest = d.Timestamp
+		}
+		orig := d.Metric + d.Tags.String()
+		key := c.Metric + c.Tags.String()
+		if prev, ok := s.origins[key]; !ok {
+			s.origins[key] = &origin{orig, d.Timestamp}
+		} else if prev.series != orig {
+			errs = append(errs, fmt.Errorf("%s: %s collides with %s as %s", s.Policy.Name(), orig, prev.series, key))
+			continue
+		} else if d.Timestamp.After(prev.seen) {
+			prev.seen = d.Timestamp
+		}
+		out = append(out, c)
+	}
+	s.sweep()
+	return
+}
+
+func (s *Sanitizer) sanitize(d *DataPoint) (*DataPoint, error) {
+	m, err := s.Policy.Metric(d.Metric)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := s.SanitizeTags(d.Tags)
+	if err != nil {
+		return nil, err
+	}
+	c := *d
+	c.Metric = m
+	c.Tags = tags
+	return &c, nil
+}
+
+// SanitizeTags returns t sanitized by the policy of s, for entries that
+// have tags but no metric, such as the metadata of an interface. Unlike
+// Sanitize, it does not detect collisions.
+func (s *Sanitizer) SanitizeTags(t Tags) (Tags, error) {
+	var err error
+	tags := make(TagSet, t.Len())
+	t.Range(func(k, v string) bool {
+		var kc, vc string
+		if kc, err = s.Policy.TagKey(k); err != nil {
+			return false
+		}
+		if vc, err = s.Policy.TagValue(v); err != nil {
+			return false
+		}
+		if _, present := tags[kc]; present {
+			err = fmt.Errorf("tag keys collide as %s", kc)
+			return false
+		}
+		tags[kc] = vc
+		return true
+	})
+	if err != nil {
+		return Tags{}, err
+	}
+	return NewTags(tags), nil
+}
@@ -0,0 +1,94 @@
+package datapoint
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNamingPolicies(t *testing.T) {
+	tests := []struct {
+		policy           NamingPolicy
+		metric, key, val string
+		m, k, v          string
+	}{
+		{OpenTSDB, "linux.disk.fs.space_free", "disk", "/mnt/my disk", "linux.disk.fs.space_free", "disk", "/mnt/mydisk"},
+		{OpenTSDB, "hw.ps", "name", "Größe", "hw.ps", "name", "Größe"},
+		{Prometheus, "linux.disk.fs.space_free", "disk", "/mnt/my disk", "linux_disk_fs_space_free", "disk", "/mnt/my disk"},
+		{Prometheus, "2xx.count", "__name", "x", "_2xx_count", "_name", "x"},
+		{Prometheus, "a-b:c", "k.é", "é", "a_b:c", "k_", "é"},
+		{Graphite, "linux.disk.fs.space_free", "disk", "/mnt/my disk", "linux.disk.fs.space_free", "disk", "_mnt_my_disk"},
+		{Graphite, "win.proc.cpu/user", "name", "a;b", "win.proc.cpu_user", "name", "a_b"},
+		{Influx, "linux.disk", "disk", "/mnt/my disk", "linux.disk", "disk", "/mnt/my disk"},
+		{Influx, "a\nb", "k", "v\t", "a_b", "k", "v_"},
+		{StatsD, "linux.disk", "disk", "/mnt/my disk", "linux.disk", "disk", "/mnt/my_disk"},
+		{StatsD, "a:b|c", "k#", "v,w", "a_b_c", "k_", "v_w"},
+	}
+	for _, test := range tests {
+		p := test.policy
+		m, err := p.Metric(test.metric)
+		if err != nil || m != test.m {
+			t.Errorf("%s: metric %q: got %q, %v; expected %q", p.Name(), test.metric, m, err, test.m)
+		}
+		k, err := p.TagKey(test.key)
+		if err != nil || k != test.k {
+			t.Errorf("%s: key %q: got %q, %v; expected %q", p.Name(), test.key, k, err, test.k)
+		}
+		v, err := p.TagValue(test.val)
+		if err != nil || v != test.v {
+			t.Errorf("%s: value %q: got %q, %v; expected %q", p.Name(), test.val, v, err, test.v)
+		}
+	}
+	for _, p := range []NamingPolicy{OpenTSDB, Prometheus, Graphite, Influx, StatsD} {
+		if _, err := p.Metric(""); err == nil {
+			t.Errorf("%s: expected error for empty metric", p.Name())
+		}
+	}
+}
+
+func TestSanitizer(t *testing.T) {
+	now := time.Now()
+	md := MultiDataPoint{
+		{Metric: "redis.used.memory", Timestamp: now, Value: 1, Tags: NewTags(TagSet{"port": "6379"})},
+		{Metric: "redis.used_memory", Timestamp: now, Value: 2, Tags: NewTags(TagSet{"port": "6379"})},
+		{Metric: "redis.used.memory", Timestamp: now, Value: 3, Tags: NewTags(TagSet{"port": "6380"})},
+		{Metric: "redis.x", Timestamp: now, Value: 4, Tags: NewTags(TagSet{"a.b": "1", "a_b": "2"})},
+	}
+	s := NewSanitizer(Prometheus)
+	out, errs := s.Sanitize(md)
+	if len(out) != 2 || len(errs) != 2 {
+		t.Fatalf("unexpected result %v, %v", out, errs)
+	}
+	if out[0].Metric != "redis_used_memory" || md[0].Metric != "redis.used.memory" {
+		t.Errorf("unexpected metric %s, original %s", out[0].Metric, md[0].Metric)
+	}
+	// Collisions are detected across batches.
+	out, errs = s.Sanitize(md[1:2])
+	if len(out) != 0 || len(errs) != 1 {
+		t.Errorf("unexpected result %v, %v", out, errs)
+	}
+	// Other policies keep the series apart.
+	out, errs = NewSanitizer(OpenTSDB).Sanitize(md)
+	if len(out) != 4 || len(errs) != 0 {
+		t.Errorf("unexpected result %v, %v", out, errs)
+	}
+}
+
+func TestSanitizerWindow(t *testing.T) {
+	now := time.Now()
+	s := NewSanitizer(Prometheus)
+	s.Window = time.Minute
+	a := &DataPoint{Metric: "a.b", Timestamp: now, Value: 1}
+	b := &DataPoint{Metric: "a_b", Timestamp: now, Value: 1}
+	if _, errs := s.Sanitize(MultiDataPoint{a, b}); len(errs) != 1 {
+		t.Fatalf("expected a collision, got %v", errs)
+	}
+	// a.b is forgotten once it was not seen for a window.
+	b.Timestamp = now.Add(time.Minute * 2)
+	if out, errs := s.Sanitize(MultiDataPoint{b}); len(out) != 0 || len(errs) != 1 {
+		t.Fatalf("expected a collision, got %v, %v", out, errs)
+	}
+	b.Timestamp = now.Add(time.Minute * 3)
+	if out, errs := s.Sanitize(MultiDataPoint{b}); len(out) != 1 || len(errs) != 0 || len(s.origins) != 1 {
+		t.Errorf("got %v, %v", out, errs)
+	}
+}
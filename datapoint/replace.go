@@ -9,6 +9,9 @@ import (
 
 // Clean is Replace with an empty replacement string.
 func Clean(s string) (string, error) {
+	if ValidTag(s) {
+		return s, nil
+	}
 	return Replace(s, "")
 }
 
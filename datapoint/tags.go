@@ -0,0 +1,327 @@
+package datapoint
+
+import (
+	"encoding/json"
+	"hash/maphash"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Tags is an immutable set of tags, sorted by key, as carried by a
+// DataPoint. Keys and values are interned and the canonical {k=v,...}
+// string is computed once and cached, so Tags are cheap to copy, compare
+// and use as map keys via String. Methods that change the set return a new
+// Tags and leave the receiver untouched. The zero value is the empty set.
+//
+// TagSet remains the mutable form used to build tags.
+type Tags struct {
+	l *tagList
+}
+
+type tagList struct {
+	// pairs holds keys and values alternately: k0, v0, k1, v1, ...
+	pairs []string
+	key   atomic.Pointer[string]
+}
+
+// NewTags returns the union of sets. Values of later sets override those of
+// earlier ones, and an empty value removes the key.
+func NewTags(sets ...TagSet) Tags {
+	n := 0
+	for _, s := range sets {
+		n += len(s)
+	}
+	if n == 0 {
+		return Tags{}
+	}
+	pairs := make([]string, 0, 2*n)
+	for _, s := range sets {
+		for k, v := range s {
+			pairs = append(pairs, Intern(k), Intern(v))
+		}
+	}
+	return makeTags(pairs)
+}
+
+// makeTags sorts pairs by key in place, keeps the last value of duplicated
+// keys and drops empty values. The strings of pairs must be interned.
+func makeTags(pairs []string) Tags {
+	// Tag sets are small, so a stable insertion sort beats sort.Sort and
+	// does not allocate.
+	for i := 2; i < len(pairs); i += 2 {
+		for j := i; j > 0 && pairs[j] < pairs[j-2]; j -= 2 {
+			pairs[j], pairs[j-2] = pairs[j-2], pairs[j]
+			pairs[j+1], pairs[j-1] = pairs[j-1], pairs[j+1]
+		}
+	}
+	out := pairs[:0]
+	for i := 0; i < len(pairs); i += 2 {
+		k, v := pairs[i], pairs[i+1]
+		if i+2 < len(pairs) && pairs[i+2] == k || v == "" {
+			continue
+		}
+		out = append(out, k, v)
+	}
+	if len(out) == 0 {
+		return Tags{}
+	}
+	return Tags{&tagList{pairs: out}}
+}
+
+// internMax bounds the number of interned strings. When a shard of the
+// table reaches its share, its strings are forgotten, so that series that
+// are gone, such as those of exited processes, do not keep their tags alive
+// forever.
+const internMax = 1 << 16
+
+// internShards is the number of shards of the table of interned strings,
+// which are locked independently since all collectors intern their tags.
+const internShards = 64
+
+type internShard struct {
+	sync.RWMutex
+	m map[string]string
+}
+
+var (
+	interned   [internShards]internShard
+	internSeed = maphash.MakeSeed()
+)
+
+// Intern returns the canonical copy of s. Interning tag keys and values
+// lets all datapoints share one copy of them instead of each holding its
+// own, which may keep a much larger string it was sliced from alive.
+func Intern(s string) string {
+	sh := &interned[maphash.String(internSeed, s)%internShards]
+	sh.RLock()
+	c, ok := sh.m[s]
+	sh.RUnlock()
+	if ok {
+		return c
+	}
+	sh.Lock()
+	defer sh.Unlock()
+	if c, ok := sh.m[s]; ok {
+		return c
+	}
+	if sh.m == nil || len(sh.m) >= internMax/internShards {
+		sh.m = make(map[string]string)
+	}
+	// Copy s so that it does not keep the string it was sliced from.
+	c = string([]byte(s))
+	sh.m[c] = c
+	return c
+}
+
+func (t Tags) pairs() []string {
+	if t.l == nil {
+		return nil
+	}
+	return t.l.pairs
+}
+
+// Len returns the number of tags in t.
+func (t Tags) Len() int {
+	return len(t.pairs()) / 2
+}
+
+// Get returns the value of key k, and whether it is present.
+func (t Tags) Get(k string) (string, bool) {
+	p := t.pairs()
+	lo, hi := 0, len(p)/2
+	for lo < hi {
+		m := (lo + hi) / 2
+		switch {
+		case p[2*m] == k:
+			return p[2*m+1], true
+		case p[2*m] < k:
+			lo = m + 1
+		default:
+			hi = m
+		}
+	}
+	return "", false
+}
+
+// Range calls f for each tag of t, sorted by key. It stops if f returns
+// false.
+func (t Tags) Range(f func(k, v string) bool) {
+	p := t.pairs()
+	for i := 0; i < len(p); i += 2 {
+		if !f(p[i], p[i+1]) {
+			return
+		}
+	}
+}
+
+// Merge returns t with the tags of o added, overriding values already in t.
+// An empty value in o removes the key. t is returned if o is empty.
+func (t Tags) Merge(o TagSet) Tags {
+	if len(o) == 0 {
+		return t
+	}
+	pairs := make([]string, len(t.pairs()), len(t.pairs())+2*len(o))
+	copy(pairs, t.pairs())
+	for k, v := range o {
+		pairs = append(pairs, Intern(k), Intern(v))
+	}
+	return makeTags(pairs)
+}
+
+// With returns t with k set to v, or with k removed if v is empty.
+func (t Tags) With(k, v string) Tags {
+	if cur, ok := t.Get(k); ok && cur == v {
+		return t
+	}
+	pairs := make([]string, len(t.pairs()), len(t.pairs())+2)
+	copy(pairs, t.pairs())
+	return makeTags(append(pairs, Intern(k), Intern(v)))
+}
+
+// Without returns t without the keys ks. t is returned if it has none of
+// them.
+func (t Tags) Without(ks ...string) Tags {
+	var pairs []string
+	p := t.pairs()
+	for i := 0; i < len(p); i += 2 {
+		drop := false
+		for _, k := range ks {
+			if p[i] == k {
+				drop = true
+				break
+			}
+		}
+		if drop && pairs == nil {
+			pairs = append(make([]string, 0, len(p)), p[:i]...)
+		} else if !drop && pairs != nil {
+			pairs = append(pairs, p[i], p[i+1])
+		}
+	}
+	if pairs == nil {
+		return t
+	}
+	if len(pairs) == 0 {
+		return Tags{}
+	}
+	return Tags{&tagList{pairs: pairs}}
+}
+
+// Copy returns the tags of t as a new, mutable TagSet.
+func (t Tags) Copy() TagSet {
+	p := t.pairs()
+	m := make(TagSet, len(p)/2)
+	for i := 0; i < len(p); i += 2 {
+		m[p[i]] = p[i+1]
+	}
+	return m
+}
+
+// Equal returns true if t and o contain the same k=v pairs.
+func (t Tags) Equal(o Tags) bool {
+	if t.l == o.l {
+		return true
+	}
+	p, q := t.pairs(), o.pairs()
+	if len(p) != len(q) {
+		return false
+	}
+	for i := range p {
+		if p[i] != q[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns t as an OpenTSDB-style {a=b,c=d} string, alphabetized by
+// key. The result is cached.
+func (t Tags) String() string {
+	if t.l == nil {
+		return "{}"
+	}
+	if s := t.l.key.Load(); s != nil {
+		return *s
+	}
+	n := 2
+	for _, s := range t.l.pairs {
+		n += len(s) + 1
+	}
+	var b strings.Builder
+	b.Grow(n)
+	b.WriteByte('{')
+	for i := 0; i < len(t.l.pairs); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(t.l.pairs[i])
+		b.WriteByte('=')
+		b.WriteString(t.l.pairs[i+1])
+	}
+	b.WriteByte('}')
+	s := b.String()
+	t.l.key.Store(&s)
+	return s
+}
+
+// Tags is identical to String() but without { and }.
+func (t Tags) Tags() string {
+	s := t.String()
+	return s[1 : len(s)-1]
+}
+
+// Clean returns t with characters that are invalid for OpenTSDB metric and
+// tag values removed. t is returned if it is already clean.
+func (t Tags) Clean() (Tags, error) {
+	for _, s := range t.pairs() {
+		if !ValidTag(s) {
+			m := t.Copy()
+			if err := m.Clean(); err != nil {
+				return t, err
+			}
+			return NewTags(m), nil
+		}
+	}
+	return t, nil
+}
+
+// MarshalJSON encodes t as a JSON object.
+func (t Tags) MarshalJSON() ([]byte, error) {
+	b := []byte{'{'}
+	for i, s := range t.pairs() {
+		if i > 0 {
+			if i%2 == 1 {
+				b = append(b, ':')
+			} else {
+				b = append(b, ',')
+			}
+		}
+		q, err := json.Marshal(s)
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, q...)
+	}
+	return append(b, '}'), nil
+}
+
+// UnmarshalJSON decodes a JSON object or null.
+func (t *Tags) UnmarshalJSON(b []byte) error {
+	var m TagSet
+	if err := json.Unmarshal(b, &m); err != nil {
+		return err
+	}
+	*t = NewTags(m)
+	return nil
+}
+
+// TagGetter is implemented by TagSet and Tags.
+type TagGetter interface {
+	Get(k string) (string, bool)
+}
+
+// Get returns the value of key k, and whether it is present.
+func (t TagSet) Get(k string) (string, bool) {
+	v, ok := t[k]
+	return v, ok
+}
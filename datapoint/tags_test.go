@@ -0,0 +1,161 @@
+package datapoint
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestTags(t *testing.T) {
+	a := NewTags(TagSet{"host": "web01", "b": "2"}, TagSet{"a": "1", "b": "3", "host": ""})
+	if s := a.String(); s != "{a=1,b=3}" {
+		t.Fatalf("got %s", s)
+	}
+	if a.Tags() != "a=1,b=3" || a.Len() != 2 {
+		t.Errorf("unexpected Tags %q or Len %d", a.Tags(), a.Len())
+	}
+	if v, ok := a.Get("b"); !ok || v != "3" {
+		t.Errorf("Get(b) = %q, %v", v, ok)
+	}
+	if _, ok := a.Get("c"); ok {
+		t.Error("Get(c) found")
+	}
+	b := a.Merge(TagSet{"c": "4", "a": ""})
+	if b.String() != "{b=3,c=4}" || a.String() != "{a=1,b=3}" {
+		t.Errorf("Merge: got %s, original %s", b, a)
+	}
+	if c := a.Merge(nil); c.l != a.l {
+		t.Error("empty Merge copied")
+	}
+	if c := a.With("a", "1"); c.l != a.l {
+		t.Error("no-op With copied")
+	}
+	if c := a.With("0", "x"); c.String() != "{0=x,a=1,b=3}" {
+		t.Errorf("With: got %s", c)
+	}
+	if c := a.Without("a", "z"); c.String() != "{b=3}" || a.Len() != 2 {
+		t.Errorf("Without: got %s", c)
+	}
+	if c := a.Without("z"); c.l != a.l {
+		t.Error("no-op Without copied")
+	}
+	if c := a.Without("a", "b"); c.Len() != 0 || c.String() != "{}" {
+		t.Errorf("Without all: got %s", c)
+	}
+	m := a.Copy()
+	m["a"] = "5"
+	if v, _ := a.Get("a"); v != "1" {
+		t.Error("Copy is not independent")
+	}
+	if !a.Equal(NewTags(TagSet{"a": "1", "b": "3"})) || a.Equal(b) || !(Tags{}).Equal(NewTags(nil)) {
+		t.Error("Equal failed")
+	}
+	var keys []string
+	a.Range(func(k, v string) bool {
+		keys = append(keys, k)
+		return true
+	})
+	if len(keys) != 2 || keys[0] != "a" || keys[1] != "b" {
+		t.Errorf("Range: got %v", keys)
+	}
+}
+
+func TestTagsClean(t *testing.T) {
+	a := NewTags(TagSet{"host": "web01"})
+	if c, err := a.Clean(); err != nil || c.l != a.l {
+		t.Errorf("clean Tags changed: %v, %v", c, err)
+	}
+	c, err := NewTags(TagSet{"disk": "/mnt/my disk"}).Clean()
+	if err != nil || c.String() != "{disk=/mnt/mydisk}" {
+		t.Errorf("got %v, %v", c, err)
+	}
+	if _, err := NewTags(TagSet{"k": "!!"}).Clean(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestTagsJSON(t *testing.T) {
+	a := NewTags(TagSet{"host": "web01", "q": `a"b`})
+	b, err := json.Marshal(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"host":"web01","q":"a\"b"}` {
+		t.Errorf("got %s", b)
+	}
+	var c Tags
+	if err := json.Unmarshal(b, &c); err != nil || !c.Equal(a) {
+		t.Errorf("got %v, %v", c, err)
+	}
+	if err := json.Unmarshal([]byte("null"), &c); err != nil || c.Len() != 0 {
+		t.Errorf("got %v, %v", c, err)
+	}
+}
+
+func TestTagsIntern(t *testing.T) {
+	line := []byte("index_name=logstash-2015.03.10")
+	a := NewTags(TagSet{"index_name": string(line[11:])})
+	if v, _ := a.Get("index_name"); v != "logstash-2015.03.10" {
+		t.Fatalf("got %q", v)
+	}
+	// A new string is copied, so that the string it was sliced from is not
+	// kept, and an interned one is shared without copying.
+	fresh := make([]string, 12)
+	for i := range fresh {
+		fresh[i] = fmt.Sprintf("web%02d.%d", i, time.Now().UnixNano())
+	}
+	i := 0
+	if n := testing.AllocsPerRun(10, func() { Intern(fresh[i]); i++ }); n < 1 {
+		t.Errorf("interning new strings: %v allocations, expected a copy", n)
+	}
+	v := string(line[11:])
+	if n := testing.AllocsPerRun(10, func() { Intern(v) }); n != 0 {
+		t.Errorf("interning an interned string: %v allocations, expected none", n)
+	}
+}
+
+var (
+	benchAddTags = TagSet{"dc": "ny"}
+	benchTags    = TagSet{"index_name": "logstash-2015.03.10", "cluster": "es01"}
+)
+
+// BenchmarkTagSetMerge is the per datapoint work of collectors.Add before
+// Tags: copying the tags, adding the host, merging AddTags and building the
+// series key.
+func BenchmarkTagSetMerge(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		tags := benchTags.Copy()
+		tags["host"] = "web01"
+		tags = benchAddTags.Copy().Merge(tags)
+		_ = tags.String()
+	}
+}
+
+// BenchmarkTagsMerge is the same work with Tags.
+func BenchmarkTagsMerge(b *testing.B) {
+	base := NewTags(benchAddTags, TagSet{"host": "web01"})
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		tags := base.Merge(benchTags)
+		_ = tags.String()
+		_ = tags.String()
+	}
+}
+
+func BenchmarkTagSetString(b *testing.B) {
+	tags := benchAddTags.Copy().Merge(benchTags)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = tags.String()
+	}
+}
+
+func BenchmarkTagsString(b *testing.B) {
+	tags := NewTags(benchAddTags, benchTags)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = tags.String()
+	}
+}
@@ -0,0 +1,211 @@
+package datapoint
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Kind is the numeric type held by a Value.
+type Kind uint8
+
+const (
+	// Invalid is the kind of the zero Value.
+	Invalid Kind = iota
+	// Int is a signed 64-bit integer.
+	Int
+	// Uint is an unsigned 64-bit integer larger than math.MaxInt64.
+	Uint
+	// Float is a finite 64-bit floating point number.
+	Float
+)
+
+var (
+	// ErrNaN is returned when parsing a NaN value.
+	ErrNaN = errors.New("value is NaN")
+	// ErrInf is returned when parsing an infinite value.
+	ErrInf = errors.New("value is infinite")
+)
+
+// Value is a typed numeric datapoint value. Integers that fit into an
+// int64 are always stored as Int, so Uint only holds values above
+// math.MaxInt64. NaN and infinite values cannot be represented.
+type Value struct {
+	kind Kind
+	i    int64
+	u    uint64
+	f    float64
+}
+
+// IntValue returns an Int Value.
+func IntValue(i int64) Value {
+	return Value{kind: Int, i: i}
+}
+
+// UintValue returns an Int or Uint Value, depending on the size of u.
+func UintValue(u uint64) Value {
+	if u <= math.MaxInt64 {
+		return IntValue(int64(u))
+	}
+	return Value{kind: Uint, u: u}
+}
+
+// FloatValue returns a Float Value. It returns ErrNaN or ErrInf if f is not
+// finite.
+func FloatValue(f float64) (Value, error) {
+	switch {
+	case math.IsNaN(f):
+		return Value{}, ErrNaN
+	case math.IsInf(f, 0):
+		return Value{}, ErrInf
+	}
+	return Value{kind: Float, f: f}, nil
+}
+
+// ParseValue converts v to a Value. It accepts Values, all integer and
+// floating point types, bools, *big.Int and strings holding a number.
+func ParseValue(v interface{}) (Value, error) {
+	switch v := v.(type) {
+	case Value:
+		if v.kind == Invalid {
+			return Value{}, fmt.Errorf("invalid value")
+		}
+		return v, nil
+	case *Value:
+		return ParseValue(*v)
+	case int:
+		return IntValue(int64(v)), nil
+	case int8:
+		return IntValue(int64(v)), nil
+	case int16:
+		return IntValue(int64(v)), nil
+	case int32:
+		return IntValue(int64(v)), nil
+	case int64:
+		return IntValue(v), nil
+	case uint:
+		return UintValue(uint64(v)), nil
+	case uint8:
+		return UintValue(uint64(v)), nil
+	case uint16:
+		return UintValue(uint64(v)), nil
+	case uint32:
+		return UintValue(uint64(v)), nil
+	case uint64:
+		return UintValue(v), nil
+	case float32:
+		return FloatValue(float64(v))
+	case float64:
+		return FloatValue(v)
+	case bool:
+		if v {
+			return IntValue(1), nil
+		}
+		return IntValue(0), nil
+	case *big.Int:
+		if v == nil {
+			return Value{}, fmt.Errorf("nil big.Int")
+		}
+		if v.IsInt64() {
+			return IntValue(v.Int64()), nil
+		}
+		if v.IsUint64() {
+			return UintValue(v.Uint64()), nil
+		}
+		f, _ := new(big.Float).SetInt(v).Float64()
+		return FloatValue(f)
+	case json.Number:
+		return ParseValue(string(v))
+	case string:
+		s := strings.TrimSpace(v)
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return IntValue(i), nil
+		}
+		if u, err := strconv.ParseUint(s, 10, 64); err == nil {
+			return UintValue(u), nil
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return FloatValue(f)
+		}
+		return Value{}, fmt.Errorf("Unparseable number %v", v)
+	}
+	return Value{}, fmt.Errorf("unsupported value type %T", v)
+}
+
+// Kind returns the kind of v.
+func (v Value) Kind() Kind {
+	return v.kind
+}
+
+// Int64 returns v as an int64. ok is false if v is not an Int.
+func (v Value) Int64() (i int64, ok bool) {
+	return v.i, v.kind == Int
+}
+
+// Uint64 returns v as a uint64. ok is false if v is negative or a Float.
+func (v Value) Uint64() (u uint64, ok bool) {
+	switch v.kind {
+	case Int:
+		return uint64(v.i), v.i >= 0
+	case Uint:
+		return v.u, true
+	}
+	return 0, false
+}
+
+// Float64 returns v as a float64, which may lose precision for large
+// integers.
+func (v Value) Float64() float64 {
+	switch v.kind {
+	case Int:
+		return float64(v.i)
+	case Uint:
+		return float64(v.u)
+	}
+	return v.f
+}
+
+// String formats v as a decimal number.
+func (v Value) String() string {
+	switch v.kind {
+	case Int:
+		return strconv.FormatInt(v.i, 10)
+	case Uint:
+		return strconv.FormatUint(v.u, 10)
+	case Float:
+		return strconv.FormatFloat(v.f, 'g', -1, 64)
+	}
+	return "<invalid>"
+}
+
+// MarshalJSON encodes v as a JSON number. Since OpenTSDB only stores
+// signed integers, Uint values are encoded as floats.
+func (v Value) MarshalJSON() ([]byte, error) {
+	switch v.kind {
+	case Int:
+		return strconv.AppendInt(nil, v.i, 10), nil
+	case Uint:
+		return json.Marshal(float64(v.u))
+	case Float:
+		return json.Marshal(v.f)
+	}
+	return nil, fmt.Errorf("invalid value")
+}
+
+// UnmarshalJSON decodes a JSON number or a string holding a number.
+func (v *Value) UnmarshalJSON(b []byte) error {
+	s := string(b)
+	if u, err := strconv.Unquote(s); err == nil {
+		s = u
+	}
+	p, err := ParseValue(s)
+	if err != nil {
+		return err
+	}
+	*v = p
+	return nil
+}
@@ -0,0 +1,93 @@
+package datapoint
+
+import (
+	"encoding/json"
+	"math"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestParseValue(t *testing.T) {
+	huge, _ := new(big.Int).SetString("100000000000000000000", 10)
+	tests := []struct {
+		in   interface{}
+		kind Kind
+		out  string
+		err  bool
+	}{
+		{1, Int, "1", false},
+		{int64(-5), Int, "-5", false},
+		{uint64(7), Int, "7", false},
+		{uint64(math.MaxUint64), Uint, "18446744073709551615", false},
+		{1.5, Float, "1.5", false},
+		{float32(0.5), Float, "0.5", false},
+		{" 42 ", Int, "42", false},
+		{"18446744073709551615", Uint, "18446744073709551615", false},
+		{"3.25", Float, "3.25", false},
+		{true, Int, "1", false},
+		{big.NewInt(12), Int, "12", false},
+		{new(big.Int).SetUint64(math.MaxUint64), Uint, "18446744073709551615", false},
+		{huge, Float, "1e+20", false},
+		{IntValue(3), Int, "3", false},
+		{json.Number("9"), Int, "9", false},
+		{math.NaN(), Invalid, "", true},
+		{math.Inf(-1), Invalid, "", true},
+		{"NaN", Invalid, "", true},
+		{"+Inf", Invalid, "", true},
+		{"abc", Invalid, "", true},
+		{"", Invalid, "", true},
+		{nil, Invalid, "", true},
+		{Value{}, Invalid, "", true},
+		{[]int{1}, Invalid, "", true},
+	}
+	for _, test := range tests {
+		v, err := ParseValue(test.in)
+		if (err != nil) != test.err {
+			t.Errorf("%#v: unexpected error state: %v", test.in, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if v.Kind() != test.kind || v.String() != test.out {
+			t.Errorf("%#v: got %v (kind %d), expected %s (kind %d)", test.in, v, v.Kind(), test.out, test.kind)
+		}
+	}
+}
+
+func TestValueJSON(t *testing.T) {
+	for _, s := range []string{`1`, `-3`, `2.5`, `"17"`} {
+		var v Value
+		if err := json.Unmarshal([]byte(s), &v); err != nil {
+			t.Errorf("%s: %v", s, err)
+		}
+	}
+	u := UintValue(math.MaxUint64)
+	b, err := json.Marshal(u)
+	if err != nil || string(b) != "18446744073709552000" {
+		t.Errorf("got %s, %v", b, err)
+	}
+	if _, err := json.Marshal(Value{}); err == nil {
+		t.Error("expected error for invalid value")
+	}
+}
+
+func TestNewDataPoint(t *testing.T) {
+	d, err := NewDataPoint("linux.mem.memfree", time.Now(), "1024", NewTags(TagSet{"host": "web 1"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := d.Value.(Value); !ok || v.Kind() != Int || d.Tags.String() != "{host=web 1}" {
+		t.Errorf("unexpected datapoint %v", d)
+	}
+	if _, err := NewDataPoint("linux.mem.memfree", time.Now(), math.NaN(), Tags{}); err == nil {
+		t.Error("expected error for NaN")
+	}
+	if _, err := NewDataPoint("", time.Now(), 1, Tags{}); err == nil {
+		t.Error("expected error for empty metric")
+	}
+	if _, err := NewDataPoint("a", time.Now(), 1, NewTags(TagSet{"": "v"})); err == nil {
+		t.Error("expected error for empty tag key")
+	}
+}
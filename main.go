@@ -1,32 +1,61 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"github.com/oliveagle/go-collectors/collectors"
+	"github.com/oliveagle/go-collectors/datapoint"
+	"github.com/oliveagle/go-collectors/metadata"
+	"github.com/oliveagle/go-collectors/slog"
+	"github.com/oliveagle/go-collectors/util"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "catalog" {
+		catalog(os.Args[2:])
+		return
+	}
 
 	// filter by collectors name
 	// c := collectors.Search("proc")
 	// list(c)
 	// cdp := collectors.Run(c)
 
+	// Read the host's procfs and sysfs where they are mounted in a
+	// container.
+	if v := os.Getenv("HOST_PROC"); v != "" {
+		collectors.ProcRoot = v
+	}
+	if v := os.Getenv("HOST_SYS"); v != "" {
+		collectors.SysRoot = v
+	}
+
 	cdp := collectors.Run(nil)
+	// Datapoints are printed in the OpenTSDB format.
+	out := newSink(datapoint.OpenTSDB)
 
 	interrupt := make(chan os.Signal, 1)
 	signal.Notify(interrupt, os.Interrupt, os.Kill, syscall.SIGTERM)
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
 
 	for {
 		select {
 		case dp := <-cdp:
-			fmt.Println(dp)
+			out.print(datapoint.MultiDataPoint{dp})
 			// fmt.Printf(".")
 
+		case <-reload:
+			if err := util.Set(); err != nil {
+				slog.Errorf("reload: host identity: %v", err)
+			}
+
 		case killSignal := <-interrupt:
+			out.print(collectors.Pipeline.Flush())
 			if killSignal == os.Interrupt {
 				fmt.Println("Daemon was interruped by system signal")
 				os.Exit(1)
@@ -37,8 +66,79 @@ func main() {
 	}
 }
 
+// sinkLogInterval is the interval after which errors of a sink that were
+// logged are logged again, and sinkMaxLogged the number of distinct errors
+// remembered meanwhile.
+const (
+	sinkLogInterval = time.Hour
+	sinkMaxLogged   = 10000
+)
+
+// A sink prints datapoints sanitized for a naming policy. Datapoints that
+// cannot be sanitized or that collide are dropped; each distinct error is
+// logged once per sinkLogInterval, since colliding series keep colliding on
+// every run.
+type sink struct {
+	sanitizer *datapoint.Sanitizer
+	logged    map[string]bool
+	reset     time.Time
+}
+
+func newSink(p datapoint.NamingPolicy) *sink {
+	return &sink{sanitizer: datapoint.NewSanitizer(p)}
+}
+
+func (s *sink) print(md datapoint.MultiDataPoint) {
+	md, errs := s.sanitizer.Sanitize(md)
+	if now := time.Now(); now.Sub(s.reset) > sinkLogInterval || len(s.logged) >= sinkMaxLogged {
+		s.logged, s.reset = make(map[string]bool), now
+	}
+	for _, err := range errs {
+		if msg := err.Error(); !s.logged[msg] {
+			s.logged[msg] = true
+			slog.Warningln(msg)
+		}
+	}
+	for _, dp := range md {
+		fmt.Println(dp)
+	}
+}
+
 func list(cs []collectors.Collector) {
 	for _, c := range cs {
 		fmt.Println(c.Name())
 	}
 }
+
+// catalog runs every collector once and prints the catalog of the metrics
+// they emitted.
+func catalog(args []string) {
+	fs := flag.NewFlagSet("catalog", flag.ExitOnError)
+	format := fs.String("f", "json", "output format: json or markdown")
+	filter := fs.String("c", "", "comma separated list of collector name patterns to run; all if empty")
+	fs.Parse(args)
+
+	cs := collectors.Search(*filter)
+	cat := metadata.NewCatalog()
+	for _, c := range cs {
+		c.Init()
+		md, err := collectors.Once(c)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", c.Name(), err)
+		}
+		cat.Record(c.Name(), md)
+	}
+	var err error
+	switch *format {
+	case "json":
+		err = cat.WriteJSON(os.Stdout)
+	case "markdown", "md":
+		err = cat.WriteMarkdown(os.Stdout)
+	default:
+		err = fmt.Errorf("unknown format %q", *format)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,145 @@
+package metadata
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/oliveagle/go-collectors/datapoint"
+)
+
+// CatalogEntry describes a metric produced by this agent.
+type CatalogEntry struct {
+	Metric     string   `json:"metric"`
+	Rate       RateType `json:"rate"`
+	Unit       Unit     `json:"unit"`
+	Desc       string   `json:"desc,omitempty"`
+	TagKeys    []string `json:"tag_keys"`
+	Collectors []string `json:"collectors"`
+}
+
+// Catalog records which metrics and tag keys are emitted by which
+// collectors. Rate, unit and description are taken from the metadata store
+// when the catalog is exported.
+type Catalog struct {
+	sync.Mutex
+	metrics map[string]*catalogMetric
+}
+
+type catalogMetric struct {
+	tagKeys    map[string]bool
+	collectors map[string]bool
+}
+
+// NewCatalog returns an empty Catalog.
+func NewCatalog() *Catalog {
+	return &Catalog{metrics: make(map[string]*catalogMetric)}
+}
+
+// Record adds the metrics of md emitted by collector to c.
+func (c *Catalog) Record(collector string, md datapoint.MultiDataPoint) {
+	c.Lock()
+	defer c.Unlock()
+	for _, dp := range md {
+		m := c.metrics[dp.Metric]
+		if m == nil {
+			m = &catalogMetric{
+				tagKeys:    make(map[string]bool),
+				collectors: make(map[string]bool),
+			}
+			c.metrics[dp.Metric] = m
+		}
+		m.collectors[collector] = true
+		dp.Tags.Range(func(k, v string) bool {
+			m.tagKeys[k] = true
+			return true
+		})
+	}
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Entries returns the catalog sorted by metric.
+func (c *Catalog) Entries() []CatalogEntry {
+	type desc struct{ tags, text string }
+	descs := make(map[string]desc)
+	metalock.Lock()
+	for k, v := range metadata {
+		if k.Name != "desc" {
+			continue
+		}
+		// Descriptions may be recorded per tag set; prefer the one with
+		// the least specific tags.
+		d, ok := descs[k.Metric]
+		if !ok || len(k.Tags) < len(d.tags) || len(k.Tags) == len(d.tags) && k.Tags < d.tags {
+			descs[k.Metric] = desc{k.Tags, fmt.Sprint(v)}
+		}
+	}
+	metalock.Unlock()
+
+	c.Lock()
+	defer c.Unlock()
+	entries := make([]CatalogEntry, 0, len(c.metrics))
+	for name, m := range c.metrics {
+		e := CatalogEntry{
+			Metric:     name,
+			TagKeys:    sortedKeys(m.tagKeys),
+			Collectors: sortedKeys(m.collectors),
+		}
+		if v, ok := Lookup(name, nil, "rate"); ok {
+			e.Rate = RateType(fmt.Sprint(v))
+		}
+		if v, ok := Lookup(name, nil, "unit"); ok {
+			e.Unit = Unit(fmt.Sprint(v))
+		}
+		e.Desc = descs[name].text
+		entries = append(entries, e)
+	}
+	sort.Sort(byMetric(entries))
+	return entries
+}
+
+type byMetric []CatalogEntry
+
+func (b byMetric) Len() int           { return len(b) }
+func (b byMetric) Less(i, j int) bool { return b[i].Metric < b[j].Metric }
+func (b byMetric) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+
+// WriteJSON writes the catalog to w as a JSON array.
+func (c *Catalog) WriteJSON(w io.Writer) error {
+	b, err := json.MarshalIndent(c.Entries(), "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", b)
+	return err
+}
+
+// WriteMarkdown writes the catalog to w as a Markdown table.
+func (c *Catalog) WriteMarkdown(w io.Writer) error {
+	if _, err := fmt.Fprintln(w, "| Metric | Rate | Unit | Tags | Collectors | Description |\n|---|---|---|---|---|---|"); err != nil {
+		return err
+	}
+	for _, e := range c.Entries() {
+		_, err := fmt.Fprintf(w, "| %s | %s | %s | %s | %s | %s |\n",
+			e.Metric, e.Rate, e.Unit,
+			strings.Join(e.TagKeys, ", "),
+			strings.Join(e.Collectors, ", "),
+			strings.Replace(e.Desc, "|", `\|`, -1),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
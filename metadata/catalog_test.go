@@ -0,0 +1,51 @@
+package metadata
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oliveagle/go-collectors/datapoint"
+)
+
+func TestCatalog(t *testing.T) {
+	reset()
+	AddMeta("linux.cpu", nil, "rate", Counter, false)
+	AddMeta("linux.cpu", nil, "unit", CHz, false)
+	AddMeta("linux.cpu", datapoint.TagSet{"type": "user"}, "desc", "Normal processes executing in user mode.", false)
+	c := NewCatalog()
+	c.Record("c_procstats_linux", datapoint.MultiDataPoint{
+		{Metric: "linux.cpu", Timestamp: time.Now(), Value: 1, Tags: datapoint.NewTags(datapoint.TagSet{"host": "a", "type": "user"})},
+		{Metric: "linux.net.stat.tcp.rtoalgorithm", Timestamp: time.Now(), Value: 1, Tags: datapoint.NewTags(datapoint.TagSet{"host": "a"})},
+	})
+	c.Record("c_other", datapoint.MultiDataPoint{
+		{Metric: "linux.cpu", Timestamp: time.Now(), Value: 1, Tags: datapoint.NewTags(datapoint.TagSet{"cpu": "0"})},
+	})
+	e := c.Entries()
+	if len(e) != 2 {
+		t.Fatalf("expected 2 entries, got %v", e)
+	}
+	cpu := e[0]
+	if cpu.Metric != "linux.cpu" || cpu.Rate != Counter || cpu.Unit != CHz || cpu.Desc == "" ||
+		strings.Join(cpu.TagKeys, ",") != "cpu,host,type" || strings.Join(cpu.Collectors, ",") != "c_other,c_procstats_linux" {
+		t.Errorf("unexpected entry: %+v", cpu)
+	}
+	if e[1].Rate != Unknown || e[1].Unit != None {
+		t.Errorf("unexpected entry: %+v", e[1])
+	}
+	var b bytes.Buffer
+	if err := c.WriteMarkdown(&b); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "| linux.cpu | counter | CentiHertz | cpu, host, type |") {
+		t.Errorf("unexpected markdown:\n%s", b.String())
+	}
+	b.Reset()
+	if err := c.WriteJSON(&b); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), `"metric": "linux.cpu"`) {
+		t.Errorf("unexpected json:\n%s", b.String())
+	}
+}
@@ -1,12 +1,18 @@
 package metadata
 
 import (
-	"github.com/oliveagle/go-collectors/datapoint"
-	// "log"
-	// "reflect"
-	// "time"
-	// "fmt"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
 	"sync"
+	"time"
+
+	"github.com/oliveagle/go-collectors/datapoint"
+	"github.com/oliveagle/go-collectors/slog"
+	"github.com/oliveagle/go-collectors/util"
 )
 
 // RateType is the type of rate for a metric: gauge, counter, or rate.
@@ -48,6 +54,7 @@ const (
 	Files               = "files"
 	Get                 = "gets"
 	GetExists           = "get exists"
+	Hertz               = "Hertz"
 	Interupt            = "interupts"
 	KBytes              = "kbytes"
 	Load                = "load"
@@ -93,47 +100,206 @@ func (m Metakey) TagSet() datapoint.TagSet {
 	return tags
 }
 
+// Metasend is the struct for sending metadata to bosun.
+type Metasend struct {
+	Metric string           `json:",omitempty"`
+	Tags   datapoint.TagSet `json:",omitempty"`
+	Name   string           `json:",omitempty"`
+	Value  interface{}
+	Time   *time.Time `json:",omitempty"`
+}
+
 var (
-	metadata  = make(map[Metakey]interface{})
+	// SendInterval is the time between two sends of changed metadata.
+	SendInterval = time.Minute
+	// Naming sanitizes the metrics and tags of metadata for bosun. Entries
+	// that cannot be sanitized or that collide are logged and not sent.
+	Naming = datapoint.NewSanitizer(datapoint.OpenTSDB)
+	// Expiry is how long metadata is kept after it was last added. Expired
+	// metadata is forgotten, and sent again if it is added again.
+	Expiry = time.Hour * 24
+	// Clock tells the time of sends and expiry. Tests may replace it with a
+	// util.FakeClock.
+	Clock util.Clock = util.SystemClock
+
+	metadata  = make(map[Metakey]*metaEntry)
+	changed   = make(map[Metakey]bool)
 	metalock  sync.Mutex
 	metahost  string
 	metafuncs []func()
 	metadebug bool
 )
 
-// AddMeta adds a metadata entry to memory, which is queued for later sending.
+type metaEntry struct {
+	value interface{}
+	added time.Time
+}
+
+// Init initializes the metadata send queue. Metadata is POSTed to the
+// /api/metadata/put route of the bosun server at u.
+func Init(u *url.URL, debug bool) error {
+	mh, err := u.Parse("/api/metadata/put")
+	if err != nil {
+		return err
+	}
+	metalock.Lock()
+	metahost = mh.String()
+	metadebug = debug
+	metalock.Unlock()
+	go collectMetadata()
+	return nil
+}
+
+// AddMetaFunc registers f to be called before every metadata send. It is
+// meant for collectors that only gather metadata periodically.
+func AddMetaFunc(f func()) {
+	metalock.Lock()
+	metafuncs = append(metafuncs, f)
+	metalock.Unlock()
+}
+
+// AddMeta adds a metadata entry to memory, which is queued for later sending
+// if it is new or its value changed. If setHost is true and tags has no host
+// key, the host tag is added. Entries that are not added again within Expiry
+// are forgotten.
 func AddMeta(metric string, tags datapoint.TagSet, name string, value interface{}, setHost bool) {
-	// fmt.Printf("AddMeta: metric: %s, tags: %v, name: %s, value: %v, setHost: %v\n", metric, tags, name, value, setHost)
-	// if tags == nil {
-	// 	tags = make(datapoint.TagSet)
-	// }
-	// if _, present := tags["host"]; setHost && !present {
-	// 	//TODO: tags["host"]
-	// 	// tags["host"] = util.Hostname
-	// }
-	// if err := tags.Clean(); err != nil {
-	// 	//TODO: slog.Error
-	// 	// slog.Error(err)
-	// 	return
-	// }
-	// ts := tags.Tags()
-	// metalock.Lock()
-	// defer metalock.Unlock()
-	// prev, present := metadata[Metakey{metric, ts, name}]
-	// if present && !reflect.DeepEqual(prev, value) {
-	// 	//TODO: slog.Infof
-	// 	// slog.Infof("metadata changed for %s/%s/%s: %v to %v", metric, ts, name, prev, value)
-
-	// 	//TODO: go sendMetadata
-	// 	// go sendMetadata([]Metasend{{
-	// 	// 	Metric: metric,
-	// 	// 	Tags:   tags,
-	// 	// 	Name:   name,
-	// 	// 	Value:  value,
-	// 	// }})
-	// } else if metadebug {
-	// 	//TODO slog.Infof
-	// 	// slog.Infof("AddMeta for %s/%s/%s: %v", metric, ts, name, value)
-	// }
-	// metadata[Metakey{metric, ts, name}] = value
+	tags = tags.Copy()
+	if _, present := tags["host"]; setHost && !present {
+		tags["host"] = util.Host()
+	}
+	if err := tags.Clean(); err != nil {
+		slog.Error(err)
+		return
+	}
+	ts := tags.Tags()
+	key := Metakey{metric, ts, name}
+	now := Clock.Now()
+	metalock.Lock()
+	defer metalock.Unlock()
+	prev, present := metadata[key]
+	if present && reflect.DeepEqual(prev.value, value) {
+		prev.added = now
+		return
+	}
+	if present {
+		slog.Infof("metadata changed for %s/%s/%s: %v to %v", metric, ts, name, prev.value, value)
+	} else if metadebug {
+		slog.Infof("AddMeta for %s/%s/%s: %v", metric, ts, name, value)
+	}
+	metadata[key] = &metaEntry{value, now}
+	changed[key] = true
+}
+
+// Lookup returns the metadata value of metric, tags and name.
+func Lookup(metric string, tags datapoint.TagSet, name string) (value interface{}, ok bool) {
+	metalock.Lock()
+	defer metalock.Unlock()
+	e, ok := metadata[Metakey{metric, tags.Tags(), name}]
+	if !ok {
+		return nil, false
+	}
+	return e.value, true
+}
+
+func collectMetadata() {
+	// Wait a bit so hopefully our collectors have run once and populated the
+	// metadata.
+	<-Clock.After(time.Second * 5)
+	for {
+		if err := send(); err != nil {
+			slog.Errorln("sending metadata:", err)
+		}
+		<-Clock.After(SendInterval)
+	}
+}
+
+// send sends all new or changed metadata. On failure, the entries are kept
+// queued for the next send.
+func send() error {
+	metalock.Lock()
+	funcs := metafuncs
+	metalock.Unlock()
+	for _, f := range funcs {
+		f()
+	}
+	metalock.Lock()
+	expire(Clock.Now())
+	if len(changed) == 0 {
+		metalock.Unlock()
+		return nil
+	}
+	host := metahost
+	ms := make([]Metasend, 0, len(changed))
+	keys := make([]Metakey, 0, len(changed))
+	for k := range changed {
+		metric, tags, err := sanitize(k)
+		if err != nil {
+			slog.Warningf("metadata %s: %v", k.Name, err)
+			continue
+		}
+		ms = append(ms, Metasend{
+			Metric: metric,
+			Tags:   tags,
+			Name:   k.Name,
+			Value:  metadata[k].value,
+		})
+		keys = append(keys, k)
+	}
+	changed = make(map[Metakey]bool)
+	metalock.Unlock()
+	if len(ms) == 0 {
+		return nil
+	}
+	err := sendMetadata(host, ms)
+	if err != nil {
+		metalock.Lock()
+		for _, k := range keys {
+			changed[k] = true
+		}
+		metalock.Unlock()
+	}
+	return err
+}
+
+// expire forgets the metadata that was not added within Expiry. metalock
+// must be held.
+func expire(now time.Time) {
+	for k, e := range metadata {
+		if now.Sub(e.added) > Expiry {
+			delete(metadata, k)
+			delete(changed, k)
+		}
+	}
+}
+
+// sanitize returns the metric and tags of k sanitized by Naming. Metadata
+// without a metric, such as the alias of an interface, only has its tags
+// sanitized.
+func sanitize(k Metakey) (string, datapoint.TagSet, error) {
+	tags := datapoint.NewTags(k.TagSet())
+	if k.Metric == "" {
+		c, err := Naming.SanitizeTags(tags)
+		return "", c.Copy(), err
+	}
+	c, errs := Naming.Sanitize(datapoint.MultiDataPoint{{Metric: k.Metric, Tags: tags}})
+	if len(errs) > 0 {
+		return "", nil, errs[0]
+	}
+	return c[0].Metric, c[0].Tags.Copy(), nil
+}
+
+func sendMetadata(host string, ms []Metasend) error {
+	b, err := json.Marshal(&ms)
+	if err != nil {
+		return err
+	}
+	resp, err := http.Post(host, "application/json", bytes.NewBuffer(b))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("bad metadata return: %s", resp.Status)
+	}
+	return nil
 }
@@ -0,0 +1,140 @@
+package metadata
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/oliveagle/go-collectors/datapoint"
+	"github.com/oliveagle/go-collectors/util"
+)
+
+func reset() {
+	metalock.Lock()
+	metadata = make(map[Metakey]*metaEntry)
+	changed = make(map[Metakey]bool)
+	metalock.Unlock()
+}
+
+func TestAddMeta(t *testing.T) {
+	reset()
+	AddMeta("linux.mem.memfree", nil, "unit", KBytes, false)
+	AddMeta("linux.mem.memfree", nil, "unit", KBytes, false)
+	AddMeta("", datapoint.TagSet{"iface": "eth0"}, "alias", "uplink", true)
+	if len(metadata) != 2 || len(changed) != 2 {
+		t.Fatalf("expected 2 deduplicated entries, got %v", metadata)
+	}
+	if v, ok := Lookup("linux.mem.memfree", nil, "unit"); !ok || v != KBytes {
+		t.Errorf("got %v, expected %v", v, KBytes)
+	}
+	if _, ok := Lookup("", datapoint.TagSet{"iface": "eth0", "host": util.Host()}, "alias"); !ok {
+		t.Error("expected host tag to be set")
+	}
+}
+
+func TestSend(t *testing.T) {
+	reset()
+	var got [][]Metasend
+	status := http.StatusNoContent
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/metadata/put" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		var ms []Metasend
+		if err := json.NewDecoder(r.Body).Decode(&ms); err != nil {
+			t.Error(err)
+		}
+		got = append(got, ms)
+		w.WriteHeader(status)
+	}))
+	defer ts.Close()
+	u, _ := url.Parse(ts.URL)
+	mh, _ := u.Parse("/api/metadata/put")
+	metahost = mh.String()
+
+	AddMeta("redis.uptime", nil, "rate", Gauge, false)
+	AddMeta("redis.uptime", nil, "unit", Second, false)
+	if err := send(); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || len(got[0]) != 2 {
+		t.Fatalf("expected 2 entries in one request, got %v", got)
+	}
+
+	// Metrics are sanitized for OpenTSDB, colliding ones are not sent.
+	AddMeta("redis.keys db0", nil, "unit", Count, false)
+	AddMeta("redis.keys db0!", nil, "unit", Count, false)
+	if err := send(); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || len(got[1]) != 1 || got[1][0].Metric != "redis.keysdb0" {
+		t.Fatalf("expected one sanitized entry, got %v", got[1:])
+	}
+	got = got[:1]
+
+	// Metadata without a metric only has its tags sanitized.
+	AddMeta("", datapoint.TagSet{"iface": "eth0 "}, "alias", "uplink", false)
+	if err := send(); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || len(got[1]) != 1 || got[1][0].Metric != "" || got[1][0].Tags["iface"] != "eth0" || got[1][0].Value != "uplink" {
+		t.Fatalf("expected the alias, got %v", got[1:])
+	}
+	got = got[:1]
+
+	// Nothing is posted if nothing is left to send.
+	AddMeta("redis.keys db0!!", nil, "unit", Count, false)
+	if err := send(); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("unexpected request: %v", got[1:])
+	}
+
+	// Unchanged metadata is not re-sent.
+	AddMeta("redis.uptime", nil, "unit", Second, false)
+	if err := send(); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("unexpected request: %v", got)
+	}
+
+	// Changes are queued until they are sent successfully.
+	AddMeta("redis.uptime", nil, "unit", MilliSecond, false)
+	status = http.StatusInternalServerError
+	if err := send(); err == nil {
+		t.Fatal("expected error")
+	}
+	status = http.StatusNoContent
+	if err := send(); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || len(got[2]) != 1 || got[2][0].Value != MilliSecond {
+		t.Fatalf("unexpected requests: %v", got)
+	}
+}
+
+func TestExpire(t *testing.T) {
+	reset()
+	clock := util.NewFakeClock(time.Unix(0, 0))
+	defer func(c util.Clock) { Clock = c }(Clock)
+	Clock = clock
+	AddMeta("redis.uptime", nil, "unit", Second, false)
+	AddMeta("redis.keys", nil, "unit", Count, false)
+	clock.Advance(Expiry)
+	AddMeta("redis.uptime", nil, "unit", Second, false)
+	clock.Advance(time.Second)
+	metalock.Lock()
+	expire(Clock.Now())
+	metalock.Unlock()
+	if _, ok := Lookup("redis.keys", nil, "unit"); ok {
+		t.Error("redis.keys did not expire")
+	}
+	if _, ok := Lookup("redis.uptime", nil, "unit"); !ok || len(changed) != 1 {
+		t.Errorf("redis.uptime expired, changed %v", changed)
+	}
+}
@@ -0,0 +1,283 @@
+package pipeline
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/oliveagle/go-collectors/datapoint"
+)
+
+// AggFunc is an aggregation function applied over a window.
+type AggFunc string
+
+const (
+	Min   AggFunc = "min"
+	Max   AggFunc = "max"
+	Avg   AggFunc = "avg"
+	Sum   AggFunc = "sum"
+	Last  AggFunc = "last"
+	Count AggFunc = "count"
+)
+
+// ParseAggFunc returns the AggFunc named s.
+func ParseAggFunc(s string) (AggFunc, error) {
+	switch f := AggFunc(s); f {
+	case Min, Max, Avg, Sum, Last, Count:
+		return f, nil
+	}
+	return "", fmt.Errorf("pipeline: unknown aggregation function %q", s)
+}
+
+// AggregateRule describes how a metric is rolled up.
+type AggregateRule struct {
+	// Metric is the metric to aggregate. A trailing * matches any suffix.
+	Metric string
+	// Window is the length of the aggregation window. Windows are aligned
+	// to multiples of Window since the epoch.
+	Window time.Duration
+	// Funcs are the functions emitted for every window.
+	Funcs []AggFunc
+	// DropTags are removed before aggregating, so that all series only
+	// differing by these tags are aggregated together: the functions apply
+	// to the last value of each of these series in the window, as
+	// cumulative counters cannot be added up over time. Last is the value
+	// with the latest timestamp across the merged series.
+	DropTags []string
+	// FuncTag, if set, is the tag key that carries the function name.
+	// Otherwise the function name is appended to the metric as a suffix,
+	// for example linux.interrupts.sum.
+	FuncTag string
+	// KeepRaw passes the raw datapoints through in addition to the
+	// aggregated ones.
+	KeepRaw bool
+}
+
+// Aggregator is a Stage that rolls series up over time windows. Datapoints
+// not matching any rule pass through untouched. A window is emitted as soon
+// as a datapoint of the same series arrives for a later window, on the first
+// Tick after its end, or on Flush.
+type Aggregator struct {
+	rules []AggregateRule
+
+	sync.Mutex
+	windows map[string]*aggWindow
+	order   []string
+}
+
+type aggWindow struct {
+	rule   *AggregateRule
+	metric string
+	tags   datapoint.Tags
+	start  time.Time
+
+	count         int64
+	sum, min, max float64
+	last          float64
+	// lastTS is the timestamp of last when merging sources.
+	lastTS time.Time
+	// sources holds the last value of each series merged by DropTags.
+	sources map[string]float64
+	// done is set once the window was emitted by Tick. It is kept to drop
+	// late datapoints.
+	done bool
+}
+
+// NewAggregator returns an Aggregator for rules. The first matching rule
+// applies to a datapoint.
+func NewAggregator(rules ...AggregateRule) (*Aggregator, error) {
+	for _, r := range rules {
+		if r.Metric == "" {
+			return nil, fmt.Errorf("pipeline: aggregate rule without metric")
+		}
+		if r.Window <= 0 {
+			return nil, fmt.Errorf("pipeline: %s: window must be positive", r.Metric)
+		}
+		if len(r.Funcs) == 0 {
+			return nil, fmt.Errorf("pipeline: %s: no aggregation functions", r.Metric)
+		}
+		for _, f := range r.Funcs {
+			if _, err := ParseAggFunc(string(f)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &Aggregator{
+		rules:   rules,
+		windows: make(map[string]*aggWindow),
+	}, nil
+}
+
+func (a *Aggregator) rule(metric string) *AggregateRule {
+	for i := range a.rules {
+		if matchMetric(a.rules[i].Metric, metric) {
+			return &a.rules[i]
+		}
+	}
+	return nil
+}
+
+// Process implements Stage.
+func (a *Aggregator) Process(collector string, md datapoint.MultiDataPoint) datapoint.MultiDataPoint {
+	a.Lock()
+	defer a.Unlock()
+	var out datapoint.MultiDataPoint
+	for _, dp := range md {
+		r := a.rule(dp.Metric)
+		if r == nil {
+			out = append(out, dp)
+			continue
+		}
+		v, ok := toFloat(dp.Value)
+		if !ok {
+			out = append(out, dp)
+			continue
+		}
+		if r.KeepRaw {
+			out = append(out, dp)
+		}
+		tags := dp.Tags.Without(r.DropTags...)
+		key := seriesKey(dp.Metric, tags)
+		start := dp.Timestamp.Truncate(r.Window)
+		w := a.windows[key]
+		if w != nil && start.After(w.start) {
+			if !w.done {
+				out = append(out, w.emit()...)
+			}
+			w = nil
+		}
+		if w == nil {
+			w = &aggWindow{
+				rule:   r,
+				metric: dp.Metric,
+				tags:   tags,
+				start:  start,
+				min:    math.Inf(1),
+				max:    math.Inf(-1),
+			}
+			if len(r.DropTags) > 0 {
+				w.sources = make(map[string]float64)
+			}
+			if _, present := a.windows[key]; !present {
+				a.order = append(a.order, key)
+			}
+			a.windows[key] = w
+		} else if start.Before(w.start) || w.done {
+			// Late datapoint for an already emitted window.
+			continue
+		}
+		if w.sources != nil {
+			w.sources[dp.Tags.String()] = v
+			if !dp.Timestamp.Before(w.lastTS) {
+				w.last = v
+				w.lastTS = dp.Timestamp
+			}
+			continue
+		}
+		w.add(v)
+	}
+	return out
+}
+
+// Tick implements Ticker. It emits the windows that ended by now, for
+// series that stopped reporting, and forgets those emitted a window ago.
+func (a *Aggregator) Tick(now time.Time) datapoint.MultiDataPoint {
+	a.Lock()
+	defer a.Unlock()
+	var out datapoint.MultiDataPoint
+	order := a.order[:0]
+	for _, k := range a.order {
+		w := a.windows[k]
+		end := w.start.Add(w.rule.Window)
+		if w.done && !now.Before(end.Add(w.rule.Window)) {
+			delete(a.windows, k)
+			continue
+		}
+		order = append(order, k)
+		if !w.done && !now.Before(end) {
+			out = append(out, w.emit()...)
+			w.done = true
+		}
+	}
+	a.order = order
+	return out
+}
+
+// Flush emits all open windows, regardless of whether they are complete.
+func (a *Aggregator) Flush() datapoint.MultiDataPoint {
+	a.Lock()
+	defer a.Unlock()
+	var out datapoint.MultiDataPoint
+	keys := a.order
+	sort.Strings(keys)
+	for _, k := range keys {
+		if w := a.windows[k]; !w.done {
+			out = append(out, w.emit()...)
+		}
+	}
+	a.windows = make(map[string]*aggWindow)
+	a.order = nil
+	return out
+}
+
+func (w *aggWindow) add(v float64) {
+	w.count++
+	w.sum += v
+	w.last = v
+	if v < w.min {
+		w.min = v
+	}
+	if v > w.max {
+		w.max = v
+	}
+}
+
+func (w *aggWindow) emit() datapoint.MultiDataPoint {
+	if w.sources != nil {
+		// Sort the sources for a stable sum.
+		keys := make([]string, 0, len(w.sources))
+		for k := range w.sources {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		last := w.last
+		for _, k := range keys {
+			w.add(w.sources[k])
+		}
+		w.last = last
+	}
+	var md datapoint.MultiDataPoint
+	for _, f := range w.rule.Funcs {
+		var v interface{}
+		switch f {
+		case Min:
+			v = w.min
+		case Max:
+			v = w.max
+		case Avg:
+			v = w.sum / float64(w.count)
+		case Sum:
+			v = w.sum
+		case Last:
+			v = w.last
+		case Count:
+			v = w.count
+		}
+		metric := w.metric
+		tags := w.tags
+		if w.rule.FuncTag != "" {
+			tags = tags.With(w.rule.FuncTag, string(f))
+		} else {
+			metric += "." + string(f)
+		}
+		md = append(md, &datapoint.DataPoint{
+			Metric:    metric,
+			Timestamp: w.start,
+			Value:     v,
+			Tags:      tags,
+		})
+	}
+	return md
+}
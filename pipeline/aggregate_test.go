@@ -0,0 +1,180 @@
+package pipeline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/oliveagle/go-collectors/datapoint"
+)
+
+func dp(metric string, ts int64, value interface{}, tags datapoint.TagSet) *datapoint.DataPoint {
+	return &datapoint.DataPoint{
+		Metric:    metric,
+		Timestamp: time.Unix(ts, 0),
+		Value:     value,
+		Tags:      datapoint.NewTags(tags),
+	}
+}
+
+func byMetric(md datapoint.MultiDataPoint) map[string]*datapoint.DataPoint {
+	m := make(map[string]*datapoint.DataPoint)
+	for _, d := range md {
+		m[seriesKey(d.Metric, d.Tags)] = d
+	}
+	return m
+}
+
+func TestAggregatorDropTags(t *testing.T) {
+	a, err := NewAggregator(AggregateRule{
+		Metric:   "linux.interrupts",
+		Window:   time.Minute,
+		Funcs:    []AggFunc{Sum, Count, Max},
+		DropTags: []string{"cpu"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := a.Process("c_procstats_linux", datapoint.MultiDataPoint{
+		dp("linux.interrupts", 0, "10", datapoint.TagSet{"type": "LOC", "cpu": "0"}),
+		dp("linux.interrupts", 0, "20", datapoint.TagSet{"type": "LOC", "cpu": "1"}),
+		dp("linux.loadavg_1_min", 0, "0.5", nil),
+	})
+	if len(out) != 1 || out[0].Metric != "linux.loadavg_1_min" {
+		t.Fatalf("expected only the pass-through point, got %v", out)
+	}
+	out = a.Process("c_procstats_linux", datapoint.MultiDataPoint{
+		dp("linux.interrupts", 15, 5, datapoint.TagSet{"type": "LOC", "cpu": "0"}),
+		dp("linux.interrupts", 60, 1, datapoint.TagSet{"type": "LOC", "cpu": "0"}),
+	})
+	m := byMetric(out)
+	if len(out) != 3 {
+		t.Fatalf("expected 3 aggregated points, got %v", out)
+	}
+	// The window aggregates the last values of cpu0 (5 at 15s) and cpu1.
+	for k, want := range map[string]interface{}{
+		"linux.interrupts.sum{type=LOC}":   25.0,
+		"linux.interrupts.count{type=LOC}": int64(2),
+		"linux.interrupts.max{type=LOC}":   20.0,
+	} {
+		d := m[k]
+		if d == nil {
+			t.Errorf("%s: missing", k)
+			continue
+		}
+		if d.Value != want {
+			t.Errorf("%s: got %v, want %v", k, d.Value, want)
+		}
+		if d.Timestamp.Unix() != 0 {
+			t.Errorf("%s: got timestamp %v", k, d.Timestamp)
+		}
+	}
+	out = a.Flush()
+	if len(out) != 3 || out[0].Timestamp.Unix() != 60 {
+		t.Fatalf("unexpected flush: %v", out)
+	}
+}
+
+func TestAggregatorDropTagsLast(t *testing.T) {
+	a, err := NewAggregator(AggregateRule{
+		Metric:   "linux.interrupts",
+		Window:   time.Minute,
+		Funcs:    []AggFunc{Last},
+		DropTags: []string{"cpu"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// cpu1 is collected last, but cpu0 has the latest datapoint.
+	a.Process("c_procstats_linux", datapoint.MultiDataPoint{
+		dp("linux.interrupts", 30, 1, datapoint.TagSet{"cpu": "0"}),
+		dp("linux.interrupts", 40, 2, datapoint.TagSet{"cpu": "0"}),
+	})
+	a.Process("c_procstats_linux", datapoint.MultiDataPoint{
+		dp("linux.interrupts", 20, 3, datapoint.TagSet{"cpu": "1"}),
+	})
+	out := a.Flush()
+	if len(out) != 1 || out[0].Metric != "linux.interrupts.last" || out[0].Value != 2.0 {
+		t.Fatalf("got %v", out)
+	}
+}
+
+func TestAggregatorTick(t *testing.T) {
+	a, err := NewAggregator(AggregateRule{Metric: "linux.loadavg_1_min", Window: time.Minute, Funcs: []AggFunc{Max}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := Pipeline{a, StageFunc(func(collector string, md datapoint.MultiDataPoint) datapoint.MultiDataPoint {
+		for _, d := range md {
+			d.Tags = d.Tags.With("collector", collector)
+		}
+		return md
+	})}
+	p.Process("c_loadavg", datapoint.MultiDataPoint{
+		dp("linux.loadavg_1_min", 10, 2, nil),
+		dp("linux.loadavg_1_min", 20, 3, nil),
+	})
+	if out := p.Tick(time.Unix(59, 0)); len(out) != 0 {
+		t.Fatalf("open window emitted: %v", out)
+	}
+	out := p.Tick(time.Unix(60, 0))
+	if len(out) != 1 || out[0].Value != 3.0 || out[0].Tags.String() != "{collector=pipeline}" {
+		t.Fatalf("got %v", out)
+	}
+	// Late datapoints of the emitted window are dropped, later ones open
+	// a new window.
+	if out := p.Process("c_loadavg", datapoint.MultiDataPoint{
+		dp("linux.loadavg_1_min", 30, 9, nil),
+		dp("linux.loadavg_1_min", 70, 4, nil),
+	}); len(out) != 0 {
+		t.Fatalf("got %v", out)
+	}
+	if out := p.Flush(); len(out) != 1 || out[0].Value != 4.0 || out[0].Timestamp.Unix() != 60 {
+		t.Fatalf("flush: got %v", out)
+	}
+	if out := p.Tick(time.Unix(200, 0)); len(out) != 0 {
+		t.Fatalf("flushed window emitted again: %v", out)
+	}
+	p.Process("c_loadavg", datapoint.MultiDataPoint{dp("linux.loadavg_1_min", 130, 1, nil)})
+	p.Tick(time.Unix(180, 0))
+	p.Tick(time.Unix(240, 0))
+	if len(a.windows) != 0 || len(a.order) != 0 {
+		t.Errorf("emitted window not forgotten: %v", a.order)
+	}
+}
+
+func TestAggregatorFuncTag(t *testing.T) {
+	a, err := NewAggregator(AggregateRule{
+		Metric:  "linux.cpu.*",
+		Window:  time.Minute,
+		Funcs:   []AggFunc{Min, Avg, Last},
+		FuncTag: "agg",
+		KeepRaw: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tags := datapoint.TagSet{"cpu": "0", "type": "user"}
+	out := a.Process("", datapoint.MultiDataPoint{
+		dp("linux.cpu.percpu", 0, 4, tags),
+		dp("linux.cpu.percpu", 30, 2, tags),
+	})
+	if len(out) != 2 {
+		t.Fatalf("expected raw points to be kept, got %v", out)
+	}
+	m := byMetric(a.Flush())
+	for fn, want := range map[string]float64{"min": 2, "avg": 3, "last": 2} {
+		d := m["linux.cpu.percpu{agg="+fn+",cpu=0,type=user}"]
+		if d == nil || d.Value != want {
+			t.Errorf("%s: got %v, want %v", fn, d, want)
+		}
+	}
+}
+
+func TestNewAggregatorInvalid(t *testing.T) {
+	if _, err := NewAggregator(AggregateRule{Metric: "a", Window: time.Minute, Funcs: []AggFunc{"median"}}); err == nil {
+		t.Error("expected error for unknown function")
+	}
+	if _, err := NewAggregator(AggregateRule{Metric: "a", Funcs: []AggFunc{Sum}}); err == nil {
+		t.Error("expected error for missing window")
+	}
+}
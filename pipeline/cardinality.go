@@ -0,0 +1,193 @@
+package pipeline
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/oliveagle/go-collectors/datapoint"
+	"github.com/oliveagle/go-collectors/slog"
+	"github.com/oliveagle/go-collectors/util"
+)
+
+const (
+	cardinalitySeries  = "collectors.cardinality.series"
+	cardinalityDropped = "collectors.cardinality.dropped"
+)
+
+// CardinalityLimiter is a Stage that limits the number of unique series
+// (metric and TagSet combinations) per metric and per collector. Series
+// not seen within Window are forgotten. Once a budget is reached, datapoints
+// of new series are dropped; datapoints of already known series always pass.
+//
+// On every Tick the limiter emits the collectors.cardinality.series gauge
+// and collectors.cardinality.dropped counter of each collector, tagged with
+// the collector.
+type CardinalityLimiter struct {
+	// MetricBudget is the default number of series allowed per metric. Zero
+	// means unlimited.
+	MetricBudget int
+	// MetricBudgets overrides MetricBudget for individual metrics.
+	MetricBudgets map[string]int
+	// CollectorBudget is the default number of series allowed per
+	// collector. Zero means unlimited.
+	CollectorBudget int
+	// CollectorBudgets overrides CollectorBudget for individual collectors.
+	CollectorBudgets map[string]int
+	// Window is how long a series is remembered after it was last seen.
+	// Defaults to one hour.
+	Window time.Duration
+	// LogInterval is the minimum time between two log lines about drops of
+	// the same collector. Defaults to ten minutes.
+	LogInterval time.Duration
+	// Clock tells the time of the window. Defaults to util.SystemClock.
+	Clock util.Clock
+
+	sync.Mutex
+	series    map[string]*cardSeries
+	metrics   map[string]int
+	colls     map[string]*cardCollector
+	lastSweep time.Time
+}
+
+type cardSeries struct {
+	collector string
+	metric    string
+	seen      time.Time
+}
+
+type cardCollector struct {
+	series   int
+	dropped  int64
+	unlogged int64
+	logged   time.Time
+}
+
+func (l *CardinalityLimiter) init() {
+	if l.series != nil {
+		return
+	}
+	if l.Clock == nil {
+		l.Clock = util.SystemClock
+	}
+	l.series = make(map[string]*cardSeries)
+	l.metrics = make(map[string]int)
+	l.colls = make(map[string]*cardCollector)
+	l.lastSweep = l.Clock.Now()
+}
+
+func (l *CardinalityLimiter) window() time.Duration {
+	if l.Window > 0 {
+		return l.Window
+	}
+	return time.Hour
+}
+
+func (l *CardinalityLimiter) logInterval() time.Duration {
+	if l.LogInterval > 0 {
+		return l.LogInterval
+	}
+	return time.Minute * 10
+}
+
+func (l *CardinalityLimiter) metricBudget(metric string) int {
+	if b, ok := l.MetricBudgets[metric]; ok {
+		return b
+	}
+	return l.MetricBudget
+}
+
+func (l *CardinalityLimiter) collectorBudget(collector string) int {
+	if b, ok := l.CollectorBudgets[collector]; ok {
+		return b
+	}
+	return l.CollectorBudget
+}
+
+func (l *CardinalityLimiter) collector(name string) *cardCollector {
+	c := l.colls[name]
+	if c == nil {
+		c = &cardCollector{}
+		l.colls[name] = c
+	}
+	return c
+}
+
+// sweep forgets all series not seen within the window.
+func (l *CardinalityLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.window()/4 {
+		return
+	}
+	l.lastSweep = now
+	cutoff := now.Add(-l.window())
+	for k, s := range l.series {
+		if s.seen.Before(cutoff) {
+			delete(l.series, k)
+			l.metrics[s.metric]--
+			l.collector(s.collector).series--
+		}
+	}
+}
+
+// Process implements Stage.
+func (l *CardinalityLimiter) Process(collector string, md datapoint.MultiDataPoint) datapoint.MultiDataPoint {
+	l.Lock()
+	defer l.Unlock()
+	l.init()
+	now := l.Clock.Now()
+	l.sweep(now)
+	c := l.collector(collector)
+	var dropped int64
+	out := make(datapoint.MultiDataPoint, 0, len(md))
+	for _, dp := range md {
+		key := seriesKey(dp.Metric, dp.Tags)
+		if s, ok := l.series[key]; ok {
+			s.seen = now
+			out = append(out, dp)
+			continue
+		}
+		if b := l.metricBudget(dp.Metric); b > 0 && l.metrics[dp.Metric] >= b {
+			dropped++
+			continue
+		}
+		if b := l.collectorBudget(collector); b > 0 && c.series >= b {
+			dropped++
+			continue
+		}
+		l.series[key] = &cardSeries{collector: collector, metric: dp.Metric, seen: now}
+		l.metrics[dp.Metric]++
+		c.series++
+		out = append(out, dp)
+	}
+	c.dropped += dropped
+	c.unlogged += dropped
+	if c.unlogged > 0 && now.Sub(c.logged) >= l.logInterval() {
+		slog.With(slog.F("collector", collector)).Warningf("cardinality: dropped %d new series since last report, tracking %d series", c.unlogged, c.series)
+		c.unlogged = 0
+		c.logged = now
+	}
+	return out
+}
+
+// Tick implements Ticker.
+func (l *CardinalityLimiter) Tick(now time.Time) datapoint.MultiDataPoint {
+	l.Lock()
+	defer l.Unlock()
+	l.init()
+	l.sweep(now)
+	names := make([]string, 0, len(l.colls))
+	for name := range l.colls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var md datapoint.MultiDataPoint
+	for _, name := range names {
+		c := l.colls[name]
+		tags := datapoint.NewTags(datapoint.TagSet{"collector": name, "host": util.Host()})
+		md = append(md,
+			&datapoint.DataPoint{Metric: cardinalitySeries, Timestamp: now, Value: c.series, Tags: tags},
+			&datapoint.DataPoint{Metric: cardinalityDropped, Timestamp: now, Value: c.dropped, Tags: tags},
+		)
+	}
+	return md
+}
@@ -0,0 +1,91 @@
+package pipeline
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/oliveagle/go-collectors/datapoint"
+	"github.com/oliveagle/go-collectors/util"
+)
+
+func indices(n int) datapoint.MultiDataPoint {
+	var md datapoint.MultiDataPoint
+	for i := 0; i < n; i++ {
+		md = append(md, dp("elastic.indices.docs.count", 0, i, datapoint.TagSet{"index_name": "logstash-" + strconv.Itoa(i)}))
+	}
+	return md
+}
+
+// process runs md of collector through l and returns the number of
+// datapoints passed and the counters of the collector on the next Tick.
+func process(l *CardinalityLimiter, collector string, md datapoint.MultiDataPoint) (series, dropped interface{}, n int) {
+	n = len(l.Process(collector, md))
+	for _, d := range l.Tick(l.Clock.Now()) {
+		if c, _ := d.Tags.Get("collector"); c != collector {
+			continue
+		}
+		switch d.Metric {
+		case cardinalitySeries:
+			series = d.Value
+		case cardinalityDropped:
+			dropped = d.Value
+		}
+	}
+	return
+}
+
+func TestCardinalityLimiterMetricBudget(t *testing.T) {
+	clock := util.NewFakeClock(time.Unix(0, 0))
+	l := &CardinalityLimiter{MetricBudget: 3, Window: time.Hour, Clock: clock}
+	series, dropped, n := process(l, "c_elasticsearch_indices", indices(5))
+	if n != 3 || series != 3 || dropped != int64(2) {
+		t.Fatalf("got %d points, series=%v dropped=%v", n, series, dropped)
+	}
+	// Known series keep passing, new ones are still dropped, and the
+	// caller's datapoints are left as they were.
+	in := indices(5)
+	for i, j := 0, len(in)-1; i < j; i, j = i+1, j-1 {
+		in[i], in[j] = in[j], in[i]
+	}
+	_, dropped, n = process(l, "c_elasticsearch_indices", in)
+	if n != 3 || dropped != int64(4) {
+		t.Fatalf("got %d points, dropped=%v", n, dropped)
+	}
+	for i, d := range in {
+		if d.Value != len(in)-1-i {
+			t.Fatalf("input modified: %v", in)
+		}
+	}
+	// After the window expires without data, the budget is free again.
+	clock.Advance(2 * time.Hour)
+	l.Process("c_other", datapoint.MultiDataPoint{dp("other", 0, 1, nil)})
+	md := indices(5)[3:]
+	if _, _, n = process(l, "c_elasticsearch_indices", md); n != 2 {
+		t.Fatalf("expected expired series to free the budget, got %d points", n)
+	}
+}
+
+// TestCardinalityLimiterPerPoint checks that collectors passing one
+// datapoint per call, such as program collectors, get no extra datapoints.
+func TestCardinalityLimiterPerPoint(t *testing.T) {
+	l := &CardinalityLimiter{MetricBudget: 3}
+	for _, d := range indices(5) {
+		if out := l.Process("c_program", datapoint.MultiDataPoint{d}); len(out) > 1 {
+			t.Fatalf("got %v", out)
+		}
+	}
+	if md := l.Tick(time.Now()); len(md) != 2 {
+		t.Errorf("got %v, expected the 2 counters of c_program", md)
+	}
+}
+
+func TestCardinalityLimiterCollectorBudget(t *testing.T) {
+	l := &CardinalityLimiter{CollectorBudget: 2, CollectorBudgets: map[string]int{"big": 10}}
+	if _, _, n := process(l, "small", indices(4)); n != 2 {
+		t.Errorf("small: expected 2 points, got %d", n)
+	}
+	if _, _, n := process(l, "big", indices(8)[4:]); n != 4 {
+		t.Errorf("big: expected 4 points, got %d", n)
+	}
+}
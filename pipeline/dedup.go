@@ -0,0 +1,113 @@
+package pipeline
+
+import (
+	"sync"
+	"time"
+
+	"github.com/oliveagle/go-collectors/datapoint"
+)
+
+// Dedup is a Stage that suppresses datapoints whose value equals the last
+// sent value of the same series. It only applies to the metrics listed in
+// Metrics; everything else passes through.
+//
+// A suppressed series is re-sent at least every Heartbeat, and the first
+// datapoint after a gap longer than Gap is always sent, so that consumers
+// can tell a constant series from a missing one. All durations are measured
+// with the datapoint timestamps.
+type Dedup struct {
+	// Metrics lists the metrics to deduplicate. A trailing * matches any
+	// suffix.
+	Metrics []string
+	// Heartbeat is the maximum time between two sent datapoints of a
+	// series. Defaults to ten minutes.
+	Heartbeat time.Duration
+	// Gap is the time after which a series is considered to have been
+	// missing. Defaults to one minute.
+	Gap time.Duration
+
+	sync.Mutex
+	series    map[string]*dedupSeries
+	lastSweep time.Time
+}
+
+type dedupSeries struct {
+	value float64
+	sent  time.Time
+	seen  time.Time
+}
+
+func (d *Dedup) heartbeat() time.Duration {
+	if d.Heartbeat > 0 {
+		return d.Heartbeat
+	}
+	return time.Minute * 10
+}
+
+func (d *Dedup) gap() time.Duration {
+	if d.Gap > 0 {
+		return d.Gap
+	}
+	return time.Minute
+}
+
+func (d *Dedup) match(metric string) bool {
+	for _, m := range d.Metrics {
+		if matchMetric(m, metric) {
+			return true
+		}
+	}
+	return false
+}
+
+// sweep forgets series that have been missing for longer than the gap;
+// their next datapoint will be sent anyway.
+func (d *Dedup) sweep(now time.Time) {
+	if now.Sub(d.lastSweep) < d.heartbeat() {
+		return
+	}
+	d.lastSweep = now
+	for k, s := range d.series {
+		if now.Sub(s.seen) > d.gap() {
+			delete(d.series, k)
+		}
+	}
+}
+
+// Process implements Stage.
+func (d *Dedup) Process(collector string, md datapoint.MultiDataPoint) datapoint.MultiDataPoint {
+	d.Lock()
+	defer d.Unlock()
+	if d.series == nil {
+		d.series = make(map[string]*dedupSeries)
+	}
+	out := make(datapoint.MultiDataPoint, 0, len(md))
+	for _, dp := range md {
+		if !d.match(dp.Metric) {
+			out = append(out, dp)
+			continue
+		}
+		v, ok := toFloat(dp.Value)
+		if !ok {
+			out = append(out, dp)
+			continue
+		}
+		ts := dp.Timestamp
+		d.sweep(ts)
+		key := seriesKey(dp.Metric, dp.Tags)
+		s := d.series[key]
+		if s != nil && s.value == v && ts.Sub(s.seen) <= d.gap() && ts.Sub(s.sent) < d.heartbeat() {
+			s.seen = ts
+			continue
+		}
+		if s == nil {
+			s = &dedupSeries{}
+			d.series[key] = s
+		}
+		s.value = v
+		s.sent = ts
+		s.seen = ts
+		out = append(out, dp)
+	}
+	return out
+}
@@ -0,0 +1,61 @@
+package pipeline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/oliveagle/go-collectors/datapoint"
+)
+
+func TestDedup(t *testing.T) {
+	d := &Dedup{
+		Metrics:   []string{"hw.*", "puppet.disabled"},
+		Heartbeat: time.Minute,
+		Gap:       30 * time.Second,
+	}
+	tags := datapoint.TagSet{"id": "0"}
+	tests := []struct {
+		ts     int64
+		metric string
+		value  interface{}
+		sent   bool
+	}{
+		{0, "hw.chassis.fan", 0, true},
+		{15, "hw.chassis.fan", 0, false},
+		{30, "hw.chassis.fan", "0", false},
+		{45, "hw.chassis.fan", 1, true},
+		{60, "hw.chassis.fan", 1, false},
+		{75, "hw.chassis.fan", 1, false},
+		{90, "hw.chassis.fan", 1, false},
+		// heartbeat
+		{105, "hw.chassis.fan", 1, true},
+		// first point after a gap
+		{150, "hw.chassis.fan", 1, true},
+		{165, "hw.chassis.fan", 1, false},
+		// not deduplicated
+		{165, "linux.uptime_total", 1, true},
+		{180, "linux.uptime_total", 1, true},
+	}
+	for i, test := range tests {
+		out := d.Process("", datapoint.MultiDataPoint{dp(test.metric, test.ts, test.value, tags)})
+		if sent := len(out) == 1; sent != test.sent {
+			t.Errorf("%d: %s at %d: sent %v, expected %v", i, test.metric, test.ts, sent, test.sent)
+		}
+	}
+}
+
+func TestDedupKeepsInput(t *testing.T) {
+	d := &Dedup{Metrics: []string{"hw.*"}, Heartbeat: time.Minute}
+	d.Process("", datapoint.MultiDataPoint{dp("hw.chassis.fan", 0, 0, nil)})
+	in := datapoint.MultiDataPoint{
+		dp("hw.chassis.fan", 15, 0, nil),
+		dp("hw.chassis.power", 15, 1, nil),
+	}
+	out := d.Process("", in)
+	if len(out) != 1 || out[0].Metric != "hw.chassis.power" {
+		t.Fatalf("got %v", out)
+	}
+	if in[0].Metric != "hw.chassis.fan" || in[1].Metric != "hw.chassis.power" {
+		t.Fatalf("input modified: %v", in)
+	}
+}
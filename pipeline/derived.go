@@ -0,0 +1,209 @@
+package pipeline
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/oliveagle/go-collectors/datapoint"
+	"github.com/oliveagle/go-collectors/metadata"
+)
+
+// DerivedMetric defines a metric computed from other series, for example:
+//
+//	DerivedMetric{
+//		Metric: "redis.keyspace_hits_pct",
+//		Expr:   "100 * redis.keyspace_hits / (redis.keyspace_hits + redis.keyspace_misses)",
+//		Rate:   metadata.Gauge,
+//		Unit:   metadata.Pct,
+//	}
+//
+// Series are joined on their tags, excluding the tag keys used in the
+// series filter of the expression; one datapoint is emitted for every tag
+// set present in all referenced series. rate(series) evaluates to the
+// per-second rate of change of a counter since its previous datapoint.
+type DerivedMetric struct {
+	Metric string
+	Expr   string
+	Rate   metadata.RateType
+	Unit   metadata.Unit
+	Desc   string
+}
+
+// Derived is a Stage that appends expression-defined metrics to batches.
+//
+// The latest value of every referenced series is kept, so that series of
+// different batches, such as those of two collectors, are joined. A derived
+// datapoint is emitted whenever a batch updates one of its series and the
+// values of the other series are at most MaxAge older, measured with the
+// datapoint timestamps; it takes the timestamp of the newest value.
+type Derived struct {
+	// MaxAge is how long the latest value of a series is joined with
+	// newer values of the other series. Defaults to one minute.
+	MaxAge time.Duration
+
+	defs []*derivedDef
+
+	sync.Mutex
+	prev      map[*seriesRef]map[string]ratePoint
+	latest    map[*seriesRef]map[string]refValue
+	newest    time.Time
+	lastSweep time.Time
+}
+
+type derivedDef struct {
+	DerivedMetric
+	root exprNode
+	refs []*seriesRef
+}
+
+type ratePoint struct {
+	value float64
+	ts    time.Time
+}
+
+// NewDerived parses the expressions of defs and returns a Derived stage.
+func NewDerived(defs ...DerivedMetric) (*Derived, error) {
+	d := &Derived{
+		prev:   make(map[*seriesRef]map[string]ratePoint),
+		latest: make(map[*seriesRef]map[string]refValue),
+	}
+	for _, def := range defs {
+		if def.Metric == "" || !datapoint.ValidTag(def.Metric) {
+			return nil, fmt.Errorf("pipeline: invalid derived metric name %q", def.Metric)
+		}
+		root, refs, err := parseExpr(def.Expr)
+		if err != nil {
+			return nil, err
+		}
+		d.defs = append(d.defs, &derivedDef{def, root, refs})
+		for _, r := range refs {
+			d.latest[r] = make(map[string]refValue)
+			if r.rate {
+				d.prev[r] = make(map[string]ratePoint)
+			}
+		}
+		if def.Rate != metadata.Unknown {
+			metadata.AddMeta(def.Metric, nil, "rate", def.Rate, false)
+		}
+		if def.Unit != metadata.None {
+			metadata.AddMeta(def.Metric, nil, "unit", def.Unit, false)
+		}
+		if def.Desc != "" {
+			metadata.AddMeta(def.Metric, nil, "desc", def.Desc, false)
+		}
+	}
+	return d, nil
+}
+
+type refValue struct {
+	value float64
+	ts    time.Time
+	tags  datapoint.Tags
+}
+
+func (d *Derived) maxAge() time.Duration {
+	if d.MaxAge > 0 {
+		return d.MaxAge
+	}
+	return time.Minute
+}
+
+// update records the values of r in md as its latest values and adds their
+// join keys to keys.
+func (d *Derived) update(r *seriesRef, md datapoint.MultiDataPoint, keys map[string]bool) {
+	for _, dp := range md {
+		if !r.match(dp) {
+			continue
+		}
+		v, ok := toFloat(dp.Value)
+		if !ok {
+			continue
+		}
+		tags := r.joinTags(dp)
+		key := tags.String()
+		if r.rate {
+			prev, seen := d.prev[r][key]
+			d.prev[r][key] = ratePoint{v, dp.Timestamp}
+			secs := dp.Timestamp.Sub(prev.ts).Seconds()
+			if !seen || secs <= 0 || v < prev.value {
+				continue
+			}
+			v = (v - prev.value) / secs
+		}
+		d.latest[r][key] = refValue{v, dp.Timestamp, tags}
+		keys[key] = true
+		if dp.Timestamp.After(d.newest) {
+			d.newest = dp.Timestamp
+		}
+	}
+}
+
+// sweep forgets the values that are too old to be joined.
+func (d *Derived) sweep() {
+	if d.newest.Sub(d.lastSweep) < d.maxAge() {
+		return
+	}
+	d.lastSweep = d.newest
+	for _, vals := range d.latest {
+		for key, v := range vals {
+			if d.newest.Sub(v.ts) > d.maxAge() {
+				delete(vals, key)
+			}
+		}
+	}
+}
+
+// Process implements Stage.
+func (d *Derived) Process(collector string, md datapoint.MultiDataPoint) datapoint.MultiDataPoint {
+	d.Lock()
+	defer d.Unlock()
+	var out datapoint.MultiDataPoint
+	for _, def := range d.defs {
+		keys := make(map[string]bool)
+		for _, r := range def.refs {
+			d.update(r, md, keys)
+		}
+		sorted := make([]string, 0, len(keys))
+		for key := range keys {
+			sorted = append(sorted, key)
+		}
+		sort.Strings(sorted)
+	Series:
+		for _, key := range sorted {
+			env := make(map[*seriesRef]float64, len(def.refs))
+			var newest refValue
+			vals := make([]refValue, 0, len(def.refs))
+			for _, r := range def.refs {
+				v, ok := d.latest[r][key]
+				if !ok {
+					continue Series
+				}
+				env[r] = v.value
+				vals = append(vals, v)
+				if len(vals) == 1 || v.ts.After(newest.ts) {
+					newest = v
+				}
+			}
+			for _, v := range vals {
+				if newest.ts.Sub(v.ts) > d.maxAge() {
+					continue Series
+				}
+			}
+			f := def.root.eval(env)
+			if math.IsNaN(f) || math.IsInf(f, 0) {
+				continue
+			}
+			out = append(out, &datapoint.DataPoint{
+				Metric:    def.Metric,
+				Timestamp: newest.ts,
+				Value:     f,
+				Tags:      newest.tags,
+			})
+		}
+	}
+	d.sweep()
+	return append(md, out...)
+}
@@ -0,0 +1,148 @@
+package pipeline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/oliveagle/go-collectors/datapoint"
+	"github.com/oliveagle/go-collectors/metadata"
+)
+
+func TestParseExpr(t *testing.T) {
+	tests := []struct {
+		expr string
+		refs int
+		err  bool
+	}{
+		{"a.b / (a.b + c_d)", 3, false},
+		{"rate(elastic.indices.search.query_time) / rate(elastic.indices.search.query_total)", 2, false},
+		{"-linux.net.conntrack.count{table=ip} * 2.5", 1, false},
+		{"a{type=hits}/a{type=*}", 2, false},
+		{`a{host=regexp(web\d{2}),type=hits} / a{host=regexp(\)+)}`, 2, false},
+		{"a{type=hits", 0, true},
+		{"1 + 2", 0, true},
+		{"a +", 0, true},
+		{"(a", 0, true},
+		{"a{type}", 0, true},
+		{"rate(1)", 0, true},
+		{"a b", 0, true},
+	}
+	for _, test := range tests {
+		_, refs, err := parseExpr(test.expr)
+		if (err != nil) != test.err {
+			t.Errorf("%s: unexpected error state: %v", test.expr, err)
+			continue
+		}
+		if len(refs) != test.refs {
+			t.Errorf("%s: got %d refs, expected %d", test.expr, len(refs), test.refs)
+		}
+	}
+}
+
+func TestDerived(t *testing.T) {
+	d, err := NewDerived(
+		DerivedMetric{
+			Metric: "redis.keyspace_hits_pct",
+			Expr:   "100 * redis.keyspace_hits / (redis.keyspace_hits + redis.keyspace_misses)",
+			Rate:   metadata.Gauge,
+			Unit:   metadata.Pct,
+		},
+		DerivedMetric{
+			Metric: "elastic.indices.search.time_per_query",
+			Expr:   "rate(elastic.indices.search.query_time) / rate(elastic.indices.search.query_total)",
+			Rate:   metadata.Gauge,
+			Unit:   metadata.MilliSecond,
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p1 := datapoint.TagSet{"port": "6379"}
+	p2 := datapoint.TagSet{"port": "6380"}
+	idx := datapoint.TagSet{"index_name": "logs"}
+	out := d.Process("c_redis", datapoint.MultiDataPoint{
+		dp("redis.keyspace_hits", 0, "30", p1),
+		dp("redis.keyspace_misses", 0, "10", p1),
+		dp("redis.keyspace_hits", 0, "0", p2),
+		dp("redis.keyspace_misses", 0, "0", p2),
+		dp("elastic.indices.search.query_time", 0, 100, idx),
+		dp("elastic.indices.search.query_total", 0, 10, idx),
+	})
+	m := byMetric(out)
+	if len(out) != 7 {
+		t.Fatalf("expected one derived point, got %v", out)
+	}
+	if d := m["redis.keyspace_hits_pct{port=6379}"]; d == nil || d.Value != 75.0 {
+		t.Errorf("bad hit ratio: %v", d)
+	}
+	out = d.Process("c_elasticsearch_indices", datapoint.MultiDataPoint{
+		dp("elastic.indices.search.query_time", 10, 400, idx),
+		dp("elastic.indices.search.query_total", 10, 30, idx),
+	})
+	m = byMetric(out)
+	if d := m["elastic.indices.search.time_per_query{index_name=logs}"]; d == nil || d.Value != 15.0 {
+		t.Errorf("bad time per query: %v", out)
+	}
+}
+
+func TestDerivedJoinFilter(t *testing.T) {
+	d, err := NewDerived(DerivedMetric{
+		Metric: "linux.net.conntrack.percent_used",
+		Expr:   "100 * linux.net.conntrack.count{table=nf} / linux.net.conntrack.max{table=nf}",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := d.Process("", datapoint.MultiDataPoint{
+		dp("linux.net.conntrack.count", 0, 5, datapoint.TagSet{"table": "nf", "host": "a"}),
+		dp("linux.net.conntrack.max", 0, 50, datapoint.TagSet{"table": "nf", "host": "a"}),
+		dp("linux.net.conntrack.max", 0, 50, datapoint.TagSet{"table": "ip", "host": "a"}),
+	})
+	m := byMetric(out)
+	if d := m["linux.net.conntrack.percent_used{host=a}"]; d == nil || d.Value != 10.0 {
+		t.Errorf("bad percent used: %v", out)
+	}
+}
+
+func TestDerivedRegexpFilter(t *testing.T) {
+	_, refs, err := parseExpr(`a{host=regexp(web\d{2})} + 1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !refs[0].match(dp("a", 0, 1, datapoint.TagSet{"host": "web01"})) || refs[0].match(dp("a", 0, 1, datapoint.TagSet{"host": "web1"})) {
+		t.Errorf("bad filter of %s", refs[0].text)
+	}
+}
+
+// TestDerivedAcrossBatches checks that series of different batches are
+// joined while their values are at most MaxAge apart.
+func TestDerivedAcrossBatches(t *testing.T) {
+	d, err := NewDerived(DerivedMetric{
+		Metric: "redis.keyspace_hits_pct",
+		Expr:   "100 * redis.keyspace_hits / (redis.keyspace_hits + redis.keyspace_misses)",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.MaxAge = time.Second * 30
+	port := datapoint.TagSet{"port": "6379"}
+	out := d.Process("c_redis_hits", datapoint.MultiDataPoint{dp("redis.keyspace_hits", 0, 30, port)})
+	if len(out) != 1 {
+		t.Fatalf("expected no derived point, got %v", out)
+	}
+	out = d.Process("c_redis_misses", datapoint.MultiDataPoint{dp("redis.keyspace_misses", 15, 10, port)})
+	m := byMetric(out)
+	if d := m["redis.keyspace_hits_pct{port=6379}"]; d == nil || d.Value != 75.0 || !d.Timestamp.Equal(time.Unix(15, 0)) {
+		t.Errorf("bad hit ratio: %v", out)
+	}
+	// The hits are stale at 45s.
+	out = d.Process("c_redis_misses", datapoint.MultiDataPoint{dp("redis.keyspace_misses", 45, 10, port)})
+	if len(out) != 1 {
+		t.Errorf("expected no derived point, got %v", out)
+	}
+	out = d.Process("c_redis_hits", datapoint.MultiDataPoint{dp("redis.keyspace_hits", 60, 90, port)})
+	m = byMetric(out)
+	if d := m["redis.keyspace_hits_pct{port=6379}"]; d == nil || d.Value != 90.0 {
+		t.Errorf("bad hit ratio: %v", out)
+	}
+}
@@ -0,0 +1,273 @@
+package pipeline
+
+import (
+	"fmt"
+	"strconv"
+	"unicode"
+
+	"github.com/oliveagle/go-collectors/datapoint"
+)
+
+// An expression is parsed from the following grammar:
+//
+//	expr    = term { ("+" | "-") term }
+//	term    = unary { ("*" | "/") unary }
+//	unary   = "-" unary | primary
+//	primary = number | "(" expr ")" | "rate(" series ")" | series
+//	series  = metric [ "{" tags "}" ]
+//
+// Metric names in expressions may only contain letters, digits, '.' and '_',
+// since '-' and '/' are operators. Tags are a datapoint.TagFilter.
+
+type exprNode interface {
+	eval(vals map[*seriesRef]float64) float64
+}
+
+type numNode float64
+
+func (n numNode) eval(map[*seriesRef]float64) float64 { return float64(n) }
+
+type negNode struct{ x exprNode }
+
+func (n negNode) eval(v map[*seriesRef]float64) float64 { return -n.x.eval(v) }
+
+type binNode struct {
+	op   byte
+	l, r exprNode
+}
+
+func (n binNode) eval(v map[*seriesRef]float64) float64 {
+	l, r := n.l.eval(v), n.r.eval(v)
+	switch n.op {
+	case '+':
+		return l + r
+	case '-':
+		return l - r
+	case '*':
+		return l * r
+	default:
+		return l / r
+	}
+}
+
+// seriesRef references the datapoints of a metric matching tags. The tag
+// keys of the filter are excluded from the key that series are joined on.
+type seriesRef struct {
+	text   string
+	metric string
+	filter *datapoint.TagFilter
+	rate   bool
+}
+
+func (s *seriesRef) eval(v map[*seriesRef]float64) float64 { return v[s] }
+
+func (s *seriesRef) match(dp *datapoint.DataPoint) bool {
+	return dp.Metric == s.metric && s.filter.Match(dp.Tags)
+}
+
+// joinTags returns the tags of dp that are used to join it with the other
+// series of an expression.
+func (s *seriesRef) joinTags(dp *datapoint.DataPoint) datapoint.Tags {
+	return dp.Tags.Without(s.filter.Keys()...)
+}
+
+type exprParser struct {
+	s    string
+	pos  int
+	refs []*seriesRef
+}
+
+// parseExpr parses s and returns its root node and all series it references.
+func parseExpr(s string) (exprNode, []*seriesRef, error) {
+	p := &exprParser{s: s}
+	n, err := p.expr()
+	if err != nil {
+		return nil, nil, err
+	}
+	p.skipSpace()
+	if p.pos != len(p.s) {
+		return nil, nil, p.errorf("unexpected %q", p.s[p.pos:])
+	}
+	if len(p.refs) == 0 {
+		return nil, nil, fmt.Errorf("expr %q: no series referenced", s)
+	}
+	return n, p.refs, nil
+}
+
+func (p *exprParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("expr %q: at %d: %s", p.s, p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *exprParser) skipSpace() {
+	for p.pos < len(p.s) && unicode.IsSpace(rune(p.s[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *exprParser) peek() byte {
+	p.skipSpace()
+	if p.pos < len(p.s) {
+		return p.s[p.pos]
+	}
+	return 0
+}
+
+func (p *exprParser) expr() (exprNode, error) {
+	l, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		if op != '+' && op != '-' {
+			return l, nil
+		}
+		p.pos++
+		r, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		l = binNode{op, l, r}
+	}
+}
+
+func (p *exprParser) term() (exprNode, error) {
+	l, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		if op != '*' && op != '/' {
+			return l, nil
+		}
+		p.pos++
+		r, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		l = binNode{op, l, r}
+	}
+}
+
+func (p *exprParser) unary() (exprNode, error) {
+	if p.peek() == '-' {
+		p.pos++
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return negNode{x}, nil
+	}
+	return p.primary()
+}
+
+func isMetricByte(c byte) bool {
+	return c == '.' || c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func (p *exprParser) primary() (exprNode, error) {
+	c := p.peek()
+	switch {
+	case c == 0:
+		return nil, p.errorf("unexpected end of expression")
+	case c == '(':
+		p.pos++
+		n, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ')' {
+			return nil, p.errorf("expected )")
+		}
+		p.pos++
+		return n, nil
+	case c >= '0' && c <= '9' || c == '.':
+		start := p.pos
+		for p.pos < len(p.s) && (p.s[p.pos] >= '0' && p.s[p.pos] <= '9' || p.s[p.pos] == '.') {
+			p.pos++
+		}
+		f, err := strconv.ParseFloat(p.s[start:p.pos], 64)
+		if err != nil {
+			return nil, p.errorf("bad number %q", p.s[start:p.pos])
+		}
+		return numNode(f), nil
+	case isMetricByte(c):
+		start := p.pos
+		for p.pos < len(p.s) && isMetricByte(p.s[p.pos]) {
+			p.pos++
+		}
+		name := p.s[start:p.pos]
+		if name == "rate" && p.peek() == '(' {
+			p.pos++
+			r, err := p.series()
+			if err != nil {
+				return nil, err
+			}
+			if p.peek() != ')' {
+				return nil, p.errorf("expected )")
+			}
+			p.pos++
+			r.rate = true
+			r.text = "rate(" + r.text + ")"
+			return r, nil
+		}
+		p.pos = start
+		return p.series()
+	}
+	return nil, p.errorf("unexpected %q", c)
+}
+
+func (p *exprParser) series() (*seriesRef, error) {
+	p.skipSpace()
+	start := p.pos
+	if p.pos < len(p.s) && p.s[p.pos] >= '0' && p.s[p.pos] <= '9' {
+		return nil, p.errorf("expected metric")
+	}
+	for p.pos < len(p.s) && isMetricByte(p.s[p.pos]) {
+		p.pos++
+	}
+	if start == p.pos {
+		return nil, p.errorf("expected metric")
+	}
+	r := &seriesRef{metric: p.s[start:p.pos]}
+	if p.pos < len(p.s) && p.s[p.pos] == '{' {
+		end := closingBrace(p.s[p.pos:])
+		if end < 0 {
+			return nil, p.errorf("expected }")
+		}
+		f, err := datapoint.ParseTagFilter(p.s[p.pos+1 : p.pos+end])
+		if err != nil {
+			return nil, p.errorf("%v", err)
+		}
+		r.filter = f
+		p.pos += end + 1
+	}
+	r.text = p.s[start:p.pos]
+	p.refs = append(p.refs, r)
+	return r, nil
+}
+
+// closingBrace returns the index of the } that closes the { at the start of
+// s, or -1. Braces inside the parentheses of filter functions, such as the
+// quantifier of regexp(web\d{2}), and escaped characters are skipped.
+func closingBrace(s string) int {
+	depth := 0
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '(':
+			depth++
+		case ')':
+			if depth > 0 {
+				depth--
+			}
+		case '}':
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,132 @@
+package pipeline
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/oliveagle/go-collectors/datapoint"
+	"github.com/oliveagle/go-collectors/metadata"
+)
+
+// UnitNormalizer is a Stage that converts values to base units (bytes,
+// seconds and hertz) based on the unit metadata of their metric:
+//
+//	kbytes       bytes    * 1024
+//	pages        bytes    * page size
+//	CentiHertz   seconds  / USER_HZ
+//	milliseconds seconds  / 1000
+//	MHz          Hertz    * 1e6
+//
+// Normalized datapoints are emitted as a new metric, named after the
+// original with the base unit appended (linux.mem.memfree.bytes), whose unit
+// metadata is set to the base unit. The original datapoints are kept unless
+// DropOriginal is set.
+type UnitNormalizer struct {
+	// Metrics limits normalization to the listed metrics. A trailing *
+	// matches any suffix. If empty, all metrics with a convertible unit are
+	// normalized.
+	Metrics []string
+	// DropOriginal drops the original datapoints of normalized metrics.
+	DropOriginal bool
+	// PageSize is the size of a memory page in bytes. Defaults to the page
+	// size of the host.
+	PageSize int64
+	// UserHZ is the number of clock ticks per second used by the kernel to
+	// report CPU times. Defaults to the USER_HZ of the host.
+	UserHZ int64
+
+	sync.Mutex
+	registered map[string]bool
+}
+
+type unitConversion struct {
+	unit   metadata.Unit
+	suffix string
+	factor func(n *UnitNormalizer) float64
+}
+
+var unitConversions = map[metadata.Unit]unitConversion{
+	metadata.KBytes:      {metadata.Bytes, "bytes", func(*UnitNormalizer) float64 { return 1024 }},
+	metadata.Page:        {metadata.Bytes, "bytes", func(n *UnitNormalizer) float64 { return float64(n.pageSize()) }},
+	metadata.CHz:         {metadata.Second, "seconds", func(n *UnitNormalizer) float64 { return 1 / float64(n.userHZ()) }},
+	metadata.MilliSecond: {metadata.Second, "seconds", func(*UnitNormalizer) float64 { return 1e-3 }},
+	metadata.MHz:         {metadata.Hertz, "hertz", func(*UnitNormalizer) float64 { return 1e6 }},
+}
+
+func (n *UnitNormalizer) pageSize() int64 {
+	if n.PageSize > 0 {
+		return n.PageSize
+	}
+	return int64(os.Getpagesize())
+}
+
+func (n *UnitNormalizer) userHZ() int64 {
+	if n.UserHZ > 0 {
+		return n.UserHZ
+	}
+	return hostUserHZ()
+}
+
+func (n *UnitNormalizer) match(metric string) bool {
+	if len(n.Metrics) == 0 {
+		return true
+	}
+	for _, m := range n.Metrics {
+		if matchMetric(m, metric) {
+			return true
+		}
+	}
+	return false
+}
+
+// register records the metadata of the normalized metric name.
+func (n *UnitNormalizer) register(metric, name string, unit metadata.Unit) {
+	n.Lock()
+	defer n.Unlock()
+	if n.registered == nil {
+		n.registered = make(map[string]bool)
+	}
+	if n.registered[name] {
+		return
+	}
+	n.registered[name] = true
+	metadata.AddMeta(name, nil, "unit", unit, false)
+	if rate, ok := metadata.Lookup(metric, nil, "rate"); ok {
+		metadata.AddMeta(name, nil, "rate", rate, false)
+	}
+}
+
+// Process implements Stage.
+func (n *UnitNormalizer) Process(collector string, md datapoint.MultiDataPoint) datapoint.MultiDataPoint {
+	var out datapoint.MultiDataPoint
+	for _, dp := range md {
+		if !n.match(dp.Metric) {
+			out = append(out, dp)
+			continue
+		}
+		u, _ := metadata.Lookup(dp.Metric, nil, "unit")
+		c, ok := unitConversions[metadata.Unit(fmt.Sprint(u))]
+		if !ok {
+			out = append(out, dp)
+			continue
+		}
+		v, ok := toFloat(dp.Value)
+		if !ok {
+			out = append(out, dp)
+			continue
+		}
+		if !n.DropOriginal {
+			out = append(out, dp)
+		}
+		name := dp.Metric + "." + c.suffix
+		n.register(dp.Metric, name, c.unit)
+		out = append(out, &datapoint.DataPoint{
+			Metric:    name,
+			Timestamp: dp.Timestamp,
+			Value:     v * c.factor(n),
+			Tags:      dp.Tags,
+		})
+	}
+	return out
+}
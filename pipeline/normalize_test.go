@@ -0,0 +1,57 @@
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/oliveagle/go-collectors/datapoint"
+	"github.com/oliveagle/go-collectors/metadata"
+)
+
+func TestHostUserHZ(t *testing.T) {
+	if hz := hostUserHZ(); hz <= 0 {
+		t.Errorf("bad USER_HZ %d", hz)
+	}
+}
+
+func TestUnitNormalizer(t *testing.T) {
+	metadata.AddMeta("linux.mem.memfree", nil, "unit", metadata.KBytes, false)
+	metadata.AddMeta("linux.proc.mem.rss", nil, "unit", metadata.Page, false)
+	metadata.AddMeta("linux.cpu", nil, "unit", metadata.CHz, false)
+	metadata.AddMeta("linux.cpu", nil, "rate", metadata.Counter, false)
+	metadata.AddMeta("os.mem.total", nil, "unit", metadata.Bytes, false)
+	n := &UnitNormalizer{PageSize: 4096, UserHZ: 100}
+	out := n.Process("", datapoint.MultiDataPoint{
+		dp("linux.mem.memfree", 0, "2", nil),
+		dp("linux.proc.mem.rss", 0, 3, datapoint.TagSet{"name": "redis"}),
+		dp("linux.cpu", 0, uint64(250), datapoint.TagSet{"type": "user"}),
+		dp("os.mem.total", 0, 1024, nil),
+	})
+	m := byMetric(out)
+	if len(out) != 7 {
+		t.Fatalf("expected originals and 3 normalized points, got %v", out)
+	}
+	for k, want := range map[string]float64{
+		"linux.mem.memfree.bytes{}":            2048,
+		"linux.proc.mem.rss.bytes{name=redis}": 12288,
+		"linux.cpu.seconds{type=user}":         2.5,
+	} {
+		if d := m[k]; d == nil || d.Value != want {
+			t.Errorf("%s: got %v, want %v", k, d, want)
+		}
+	}
+	if u, _ := metadata.Lookup("linux.cpu.seconds", nil, "unit"); u != metadata.Unit(metadata.Second) {
+		t.Errorf("unexpected unit %v", u)
+	}
+	if r, _ := metadata.Lookup("linux.cpu.seconds", nil, "rate"); r != metadata.Counter {
+		t.Errorf("unexpected rate %v", r)
+	}
+
+	n = &UnitNormalizer{Metrics: []string{"linux.mem.*"}, DropOriginal: true}
+	out = n.Process("", datapoint.MultiDataPoint{
+		dp("linux.mem.memfree", 0, 1, nil),
+		dp("linux.cpu", 0, 1, nil),
+	})
+	if len(out) != 2 || out[0].Metric != "linux.mem.memfree.bytes" || out[1].Metric != "linux.cpu" {
+		t.Errorf("unexpected output: %v", out)
+	}
+}
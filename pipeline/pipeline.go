@@ -0,0 +1,102 @@
+// Package pipeline provides processing stages that are applied to the
+// datapoints produced by collectors before they leave the host.
+package pipeline
+
+import (
+	"strings"
+	"time"
+
+	"github.com/oliveagle/go-collectors/datapoint"
+)
+
+// Stage transforms the datapoints produced by a single run of a collector.
+// Implementations must be safe for concurrent use, since collectors run in
+// their own goroutines.
+type Stage interface {
+	Process(collector string, md datapoint.MultiDataPoint) datapoint.MultiDataPoint
+}
+
+// StageFunc adapts an ordinary function to the Stage interface.
+type StageFunc func(collector string, md datapoint.MultiDataPoint) datapoint.MultiDataPoint
+
+// Process calls f(collector, md).
+func (f StageFunc) Process(collector string, md datapoint.MultiDataPoint) datapoint.MultiDataPoint {
+	return f(collector, md)
+}
+
+// A Ticker is a Stage that emits datapoints on its own, such as windows
+// that ended or statistics of the stage. Tick is called periodically.
+type Ticker interface {
+	Stage
+	Tick(now time.Time) datapoint.MultiDataPoint
+}
+
+// A Flusher is a Stage that holds datapoints back. Flush emits them at
+// shutdown.
+type Flusher interface {
+	Stage
+	Flush() datapoint.MultiDataPoint
+}
+
+// TickCollector is the collector name under which the datapoints of Tick
+// and Flush run through the stages after the one that emitted them.
+const TickCollector = "pipeline"
+
+// Pipeline is an ordered list of stages.
+type Pipeline []Stage
+
+// Process runs md through every stage of p in order. An empty pipeline
+// returns md unchanged.
+func (p Pipeline) Process(collector string, md datapoint.MultiDataPoint) datapoint.MultiDataPoint {
+	for _, s := range p {
+		if len(md) == 0 {
+			break
+		}
+		md = s.Process(collector, md)
+	}
+	return md
+}
+
+// Tick calls Tick of the Tickers of p and runs the datapoints they emit
+// through the stages after them.
+func (p Pipeline) Tick(now time.Time) datapoint.MultiDataPoint {
+	var out datapoint.MultiDataPoint
+	for i, s := range p {
+		if t, ok := s.(Ticker); ok {
+			out = append(out, p[i+1:].Process(TickCollector, t.Tick(now))...)
+		}
+	}
+	return out
+}
+
+// Flush calls Flush of the Flushers of p, in order, and runs the
+// datapoints they emit through the stages after them.
+func (p Pipeline) Flush() datapoint.MultiDataPoint {
+	var out datapoint.MultiDataPoint
+	for i, s := range p {
+		if f, ok := s.(Flusher); ok {
+			out = append(out, p[i+1:].Process(TickCollector, f.Flush())...)
+		}
+	}
+	return out
+}
+
+// seriesKey uniquely identifies the series of metric with tags.
+func seriesKey(metric string, tags datapoint.Tags) string {
+	return metric + tags.String()
+}
+
+// matchMetric reports whether metric matches pattern. A trailing * in pattern
+// matches any suffix.
+func matchMetric(pattern, metric string) bool {
+	if strings.HasSuffix(pattern, "*") {
+		return strings.HasPrefix(metric, pattern[:len(pattern)-1])
+	}
+	return pattern == metric
+}
+
+// toFloat converts the value of a datapoint to a float64.
+func toFloat(v interface{}) (float64, bool) {
+	n, err := datapoint.ParseValue(v)
+	return n.Float64(), err == nil
+}
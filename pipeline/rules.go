@@ -0,0 +1,265 @@
+package pipeline
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oliveagle/go-collectors/datapoint"
+	"github.com/oliveagle/go-collectors/metadata"
+	"github.com/oliveagle/go-collectors/slog"
+	"github.com/oliveagle/go-collectors/util"
+	"gopkg.in/yaml.v1"
+)
+
+const alertState = "alert.state"
+
+// HookTimeout is the timeout of rule hook commands.
+var HookTimeout = time.Second * 10
+
+// Rule is a local threshold rule. Cond has the form "metric op threshold",
+// where metric may end in * and may be followed by a datapoint.TagFilter in
+// braces, and op is one of < <= > >= == !=. For example:
+//
+//	os.disk.fs.percent_free < 5
+//	linux.net.bond.slave.is_up{bond=bond0} == 0
+//	hw.* != 0
+//
+// A series becomes critical once Cond has held for at least For. Hook, if
+// set, is a command and its arguments, which are called through
+// util.Command with the rule name, the new state ("critical" or "normal"),
+// the metric, its tags and value appended.
+type Rule struct {
+	Name string
+	Cond string
+	For  time.Duration
+	Hook []string
+}
+
+type ruleConfig struct {
+	Name string   `yaml:"name"`
+	Cond string   `yaml:"cond"`
+	For  string   `yaml:"for"`
+	Hook []string `yaml:"hook"`
+}
+
+// ParseRules parses rules from YAML of the form:
+//
+//	- name: disk_full
+//	  cond: os.disk.fs.percent_free < 5
+//	  for: 5m
+//	  hook: [/usr/local/bin/notify, disk]
+func ParseRules(b []byte) ([]Rule, error) {
+	var cfg []ruleConfig
+	if err := yaml.Unmarshal(b, &cfg); err != nil {
+		return nil, err
+	}
+	var rules []Rule
+	for _, c := range cfg {
+		r := Rule{Name: c.Name, Cond: c.Cond, Hook: c.Hook}
+		if c.For != "" {
+			d, err := time.ParseDuration(c.For)
+			if err != nil {
+				return nil, fmt.Errorf("rule %s: %v", c.Name, err)
+			}
+			r.For = d
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+var condRE = regexp.MustCompile(`^\s*([^\s{<>=!]+)(\{[^}]*\})?\s*(<=|>=|==|!=|<|>)\s*(\S+)\s*$`)
+
+type rule struct {
+	Rule
+	metric    string
+	filter    *datapoint.TagFilter
+	op        string
+	threshold float64
+}
+
+func compileRule(r Rule) (*rule, error) {
+	if r.Name == "" || !datapoint.ValidTag(r.Name) {
+		return nil, fmt.Errorf("rule: invalid name %q", r.Name)
+	}
+	m := condRE.FindStringSubmatch(r.Cond)
+	if m == nil {
+		return nil, fmt.Errorf("rule %s: bad condition %q", r.Name, r.Cond)
+	}
+	c := &rule{Rule: r, metric: m[1], op: m[3]}
+	if m[2] != "" {
+		f, err := datapoint.ParseTagFilter(m[2][1 : len(m[2])-1])
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %v", r.Name, err)
+		}
+		c.filter = f
+	}
+	f, err := strconv.ParseFloat(m[4], 64)
+	if err != nil {
+		return nil, fmt.Errorf("rule %s: bad threshold %q", r.Name, m[4])
+	}
+	c.threshold = f
+	return c, nil
+}
+
+func (r *rule) match(dp *datapoint.DataPoint) bool {
+	return matchMetric(r.metric, dp.Metric) && r.filter.Match(dp.Tags)
+}
+
+func (r *rule) holds(v float64) bool {
+	switch r.op {
+	case "<":
+		return v < r.threshold
+	case "<=":
+		return v <= r.threshold
+	case ">":
+		return v > r.threshold
+	case ">=":
+		return v >= r.threshold
+	case "==":
+		return v == r.threshold
+	default:
+		return v != r.threshold
+	}
+}
+
+// RuleEvaluator is a Stage that evaluates rules against the latest value of
+// every matching series. For every evaluated series it appends an
+// alert.state datapoint, tagged with the alert name and the metric, whose
+// value is 0 while normal and 1 while critical.
+//
+// The state of a series that was not seen for StaleTicks pipeline ticks is
+// dropped, so that a series that disappears while critical does not stay
+// critical forever.
+type RuleEvaluator struct {
+	// StaleTicks is the number of ticks after which the state of a series
+	// that was not seen is dropped. Defaults to 10.
+	StaleTicks int
+
+	rules []*rule
+
+	sync.Mutex
+	states map[string]*ruleState
+	ticks  int
+}
+
+type ruleState struct {
+	since    time.Time
+	critical bool
+	// seen is the number of ticks of the evaluator when the series was last
+	// seen.
+	seen int
+}
+
+// NewRuleEvaluator compiles rules and returns a RuleEvaluator.
+func NewRuleEvaluator(rules ...Rule) (*RuleEvaluator, error) {
+	e := &RuleEvaluator{states: make(map[string]*ruleState)}
+	for _, r := range rules {
+		c, err := compileRule(r)
+		if err != nil {
+			return nil, err
+		}
+		e.rules = append(e.rules, c)
+	}
+	metadata.AddMeta(alertState, nil, "rate", metadata.Gauge, false)
+	metadata.AddMeta(alertState, nil, "unit", metadata.Ok, false)
+	return e, nil
+}
+
+// Process implements Stage.
+func (e *RuleEvaluator) Process(collector string, md datapoint.MultiDataPoint) datapoint.MultiDataPoint {
+	e.Lock()
+	defer e.Unlock()
+	var out datapoint.MultiDataPoint
+	for _, r := range e.rules {
+		for _, dp := range md {
+			if !r.match(dp) {
+				continue
+			}
+			v, ok := toFloat(dp.Value)
+			if !ok {
+				continue
+			}
+			key := r.Name + " " + seriesKey(dp.Metric, dp.Tags)
+			s := e.states[key]
+			if s == nil {
+				s = &ruleState{}
+				e.states[key] = s
+			}
+			s.seen = e.ticks
+			was := s.critical
+			if r.holds(v) {
+				if s.since.IsZero() {
+					s.since = dp.Timestamp
+				}
+				s.critical = dp.Timestamp.Sub(s.since) >= r.For
+			} else {
+				s.since = time.Time{}
+				s.critical = false
+			}
+			if s.critical != was {
+				e.transition(r, dp, s.critical)
+			}
+			state := 0
+			if s.critical {
+				state = 1
+			}
+			tags := dp.Tags.Merge(datapoint.TagSet{"alert": r.Name, "metric": dp.Metric})
+			out = append(out, &datapoint.DataPoint{
+				Metric:    alertState,
+				Timestamp: dp.Timestamp,
+				Value:     state,
+				Tags:      tags,
+			})
+		}
+	}
+	return append(md, out...)
+}
+
+func (e *RuleEvaluator) staleTicks() int {
+	if e.StaleTicks > 0 {
+		return e.StaleTicks
+	}
+	return 10
+}
+
+// Tick implements Ticker. It drops the states of series that were not seen
+// for StaleTicks ticks.
+func (e *RuleEvaluator) Tick(now time.Time) datapoint.MultiDataPoint {
+	e.Lock()
+	defer e.Unlock()
+	e.ticks++
+	for key, s := range e.states {
+		if e.ticks-s.seen <= e.staleTicks() {
+			continue
+		}
+		if s.critical {
+			slog.Infof("rule %s: not seen for %d ticks, forgetting its critical state", key, e.staleTicks())
+		}
+		delete(e.states, key)
+	}
+	return nil
+}
+
+func (e *RuleEvaluator) transition(r *rule, dp *datapoint.DataPoint, critical bool) {
+	state := "normal"
+	if critical {
+		state = "critical"
+	}
+	slog.Infof("rule %s: %s%s is %s: %v %s %v", r.Name, dp.Metric, dp.Tags, state, dp.Value, r.op, r.threshold)
+	if len(r.Hook) == 0 {
+		return
+	}
+	args := append(append([]string{}, r.Hook[1:]...), r.Name, state, dp.Metric, dp.Tags.Tags(), fmt.Sprint(dp.Value))
+	go runHook(r.Hook[0], args...)
+}
+
+var runHook = func(name string, args ...string) {
+	if _, err := util.Command(HookTimeout, name, args...); err != nil {
+		slog.Errorf("rule hook %s %s: %v", name, strings.Join(args, " "), err)
+	}
+}
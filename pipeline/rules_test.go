@@ -0,0 +1,113 @@
+package pipeline
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oliveagle/go-collectors/datapoint"
+)
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules([]byte(`
+- name: disk_full
+  cond: os.disk.fs.percent_free < 5
+  for: 5m
+  hook: [/usr/local/bin/notify, disk]
+- name: hw
+  cond: hw.* != 0
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 2 || rules[0].For != 5*time.Minute || len(rules[0].Hook) != 2 || rules[1].Cond != "hw.* != 0" {
+		t.Fatalf("unexpected rules: %+v", rules)
+	}
+	if _, err := NewRuleEvaluator(rules...); err != nil {
+		t.Fatal(err)
+	}
+	for _, cond := range []string{"a.b", "a.b < x", "a{b} > 1", "< 5"} {
+		if _, err := NewRuleEvaluator(Rule{Name: "bad", Cond: cond}); err == nil {
+			t.Errorf("%s: expected error", cond)
+		}
+	}
+}
+
+func TestRuleEvaluator(t *testing.T) {
+	hooks := make(chan string, 10)
+	prev := runHook
+	t.Cleanup(func() { runHook = prev })
+	runHook = func(name string, args ...string) {
+		hooks <- name + " " + strings.Join(args, " ")
+	}
+	e, err := NewRuleEvaluator(
+		Rule{Name: "disk_full", Cond: "os.disk.fs.percent_free < 5", For: 30 * time.Second, Hook: []string{"notify"}},
+		Rule{Name: "bond_down", Cond: "linux.net.bond.slave.is_up{bond=bond0} == 0"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	disk := datapoint.TagSet{"disk": "/"}
+	tests := []struct {
+		ts    int64
+		value interface{}
+		state int
+		hook  string
+	}{
+		{0, 10, 0, ""},
+		{15, "4.5", 0, ""},
+		{30, 3, 0, ""},
+		{45, 2, 1, "notify disk_full critical os.disk.fs.percent_free disk=/ 2"},
+		{60, 1, 1, ""},
+		{75, 50, 0, "notify disk_full normal os.disk.fs.percent_free disk=/ 50"},
+		{90, 1, 0, ""},
+	}
+	for _, test := range tests {
+		out := e.Process("", datapoint.MultiDataPoint{dp("os.disk.fs.percent_free", test.ts, test.value, disk)})
+		if len(out) != 2 || out[1].Metric != alertState {
+			t.Fatalf("%d: unexpected output %v", test.ts, out)
+		}
+		a := out[1]
+		if a.Value != test.state || a.Tags.Tags() != "alert=disk_full,disk=/,metric=os.disk.fs.percent_free" {
+			t.Errorf("%d: got %v, expected state %d", test.ts, a, test.state)
+		}
+		if test.hook != "" {
+			select {
+			case h := <-hooks:
+				if h != test.hook {
+					t.Errorf("%d: got hook %q, expected %q", test.ts, h, test.hook)
+				}
+			case <-time.After(time.Second):
+				t.Errorf("%d: hook not called", test.ts)
+			}
+		}
+	}
+	out := e.Process("", datapoint.MultiDataPoint{
+		dp("linux.net.bond.slave.is_up", 0, 0, datapoint.TagSet{"bond": "bond0", "slave": "eth0"}),
+		dp("linux.net.bond.slave.is_up", 0, 0, datapoint.TagSet{"bond": "bond1", "slave": "eth1"}),
+	})
+	if len(out) != 3 || out[2].Value != 1 || out[2].Tags.Copy()["slave"] != "eth0" {
+		t.Errorf("unexpected bond output: %v", out)
+	}
+}
+
+func TestRuleEvaluatorStale(t *testing.T) {
+	e, err := NewRuleEvaluator(Rule{Name: "bond_down", Cond: "linux.net.bond.slave.is_up == 0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.StaleTicks = 2
+	eth0 := datapoint.TagSet{"slave": "eth0"}
+	eth1 := datapoint.TagSet{"slave": "eth1"}
+	e.Process("", datapoint.MultiDataPoint{
+		dp("linux.net.bond.slave.is_up", 0, 0, eth0),
+		dp("linux.net.bond.slave.is_up", 0, 0, eth1),
+	})
+	for i := 0; i < 3; i++ {
+		e.Tick(time.Unix(int64(i), 0))
+		e.Process("", datapoint.MultiDataPoint{dp("linux.net.bond.slave.is_up", int64(i), 0, eth1)})
+	}
+	if len(e.states) != 1 {
+		t.Errorf("expected the state of eth0 to be dropped, got %v", e.states)
+	}
+}
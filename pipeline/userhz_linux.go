@@ -0,0 +1,54 @@
+package pipeline
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"strconv"
+	"sync"
+	"unsafe"
+)
+
+// atClkTck is the auxiliary vector entry holding the frequency of times().
+const atClkTck = 17
+
+// nativeEndian is the byte order of the host, in which the kernel writes
+// the auxiliary vector.
+var nativeEndian binary.ByteOrder = func() binary.ByteOrder {
+	x := uint16(1)
+	if *(*byte)(unsafe.Pointer(&x)) == 1 {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}()
+
+var (
+	userHZ     int64
+	userHZOnce sync.Once
+)
+
+// hostUserHZ returns USER_HZ, read from the auxiliary vector of the process.
+func hostUserHZ() int64 {
+	userHZOnce.Do(func() {
+		userHZ = 100
+		b, err := ioutil.ReadFile("/proc/self/auxv")
+		if err != nil {
+			return
+		}
+		word := strconv.IntSize / 8
+		read := func(b []byte) uint64 {
+			if word == 4 {
+				return uint64(nativeEndian.Uint32(b))
+			}
+			return nativeEndian.Uint64(b)
+		}
+		for len(b) >= 2*word {
+			k, v := read(b), read(b[word:])
+			b = b[2*word:]
+			if k == atClkTck && v > 0 {
+				userHZ = int64(v)
+				return
+			}
+		}
+	})
+	return userHZ
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+// +build !linux
+
+package pipeline
+
+// hostUserHZ returns the USER_HZ commonly used by unix kernels.
+func hostUserHZ() int64 {
+	return 100
+}
@@ -0,0 +1,99 @@
+package slog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// JSONLog writes one JSON object per line to W, for example:
+//
+//	{"time":"2015-03-10T12:00:00Z","level":"error","caller":"interval.go:54","msg":"connection refused","collector":"c_redis_linux"}
+//
+// Fields are written after msg in the order they were given. Errors and
+// fmt.Stringers are written as strings, other values as encoding/json
+// encodes them.
+type JSONLog struct {
+	W io.Writer
+
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+// SetJSON configures slog to write JSON to w.
+func SetJSON(w io.Writer) {
+	Set(&JSONLog{W: w})
+}
+
+// Handle implements Handler.
+func (j *JSONLog) Handle(e *Entry) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	b := &j.buf
+	b.Reset()
+	b.WriteString(`{"time":`)
+	writeJSON(b, e.Time.UTC().Format(time.RFC3339Nano))
+	b.WriteString(`,"level":`)
+	writeJSON(b, e.Level.String())
+	if e.Caller != "" {
+		b.WriteString(`,"caller":`)
+		writeJSON(b, e.Caller)
+	}
+	b.WriteString(`,"msg":`)
+	writeJSON(b, e.Msg)
+	for _, f := range e.Fields {
+		b.WriteByte(',')
+		writeJSON(b, f.Key)
+		b.WriteByte(':')
+		switch v := f.Value.(type) {
+		case error, fmt.Stringer:
+			writeJSON(b, fieldString(v))
+		default:
+			writeJSON(b, v)
+		}
+	}
+	b.WriteString("}\n")
+	j.W.Write(b.Bytes())
+}
+
+func writeJSON(b *bytes.Buffer, v interface{}) {
+	m, err := json.Marshal(v)
+	if err != nil {
+		m, _ = json.Marshal(fieldString(v))
+	}
+	b.Write(m)
+}
+
+func (j *JSONLog) log(l Level, v string) {
+	j.Handle(&Entry{Time: time.Now(), Level: l, Msg: rmNl(v)})
+}
+
+// Fatal logs a fatal message and calls os.Exit(1).
+func (j *JSONLog) Fatal(v string) {
+	j.log(LevelFatal, v)
+	os.Exit(1)
+}
+
+// Error logs an error message.
+func (j *JSONLog) Error(v string) {
+	j.log(LevelError, v)
+}
+
+// Info logs an info message.
+func (j *JSONLog) Info(v string) {
+	j.log(LevelInfo, v)
+}
+
+// Warning logs a warning message.
+func (j *JSONLog) Warning(v string) {
+	j.log(LevelWarning, v)
+}
+
+// Debug logs a debug message.
+func (j *JSONLog) Debug(v string) {
+	j.log(LevelDebug, v)
+}
@@ -3,15 +3,23 @@
 // using the log package of the standard library, but can easily be used with
 // other logging backends. Thus, we can use syslog on unicies and the event log
 // on windows.
+//
+// Messages have a Level, filtered at runtime with SetLevel, and may carry
+// key/value Fields added with With. Backends that implement Handler, such as
+// JSONLog, receive them as structured Entries; other Loggers receive them
+// formatted as text.
 package slog
 
 import (
+	"bytes"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync/atomic"
+	"time"
 )
 
 var (
@@ -19,6 +27,87 @@ var (
 	LogLineNumber = true
 )
 
+// Level is the severity of a log message.
+type Level int32
+
+// Log levels, in increasing severity.
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarning
+	LevelError
+	LevelFatal
+)
+
+var levelNames = []string{"debug", "info", "warning", "error", "fatal"}
+
+func (l Level) String() string {
+	if l < LevelDebug || l > LevelFatal {
+		return fmt.Sprintf("level(%d)", int32(l))
+	}
+	return levelNames[l]
+}
+
+// ParseLevel parses a level name as returned by Level.String. "warn" is
+// accepted for LevelWarning.
+func ParseLevel(s string) (Level, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "warn" {
+		return LevelWarning, nil
+	}
+	for i, n := range levelNames {
+		if s == n {
+			return Level(i), nil
+		}
+	}
+	return 0, fmt.Errorf("slog: unknown level %q", s)
+}
+
+var level = int32(LevelInfo)
+
+// SetLevel sets the minimum level of messages that are logged. It defaults
+// to LevelInfo and may be changed at any time. Fatal messages are always
+// logged.
+func SetLevel(l Level) {
+	atomic.StoreInt32(&level, int32(l))
+}
+
+// GetLevel returns the minimum level of messages that are logged.
+func GetLevel() Level {
+	return Level(atomic.LoadInt32(&level))
+}
+
+// Enabled reports whether messages of level l are logged.
+func Enabled(l Level) bool {
+	return l >= LevelFatal || l >= GetLevel()
+}
+
+// Field is a key/value pair attached to a log message.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// F returns a Field.
+func F(key string, value interface{}) Field {
+	return Field{key, value}
+}
+
+// Err returns an "err" Field for err.
+func Err(err error) Field {
+	return Field{"err", err}
+}
+
+// Entry is a single log message with its metadata.
+type Entry struct {
+	Time  time.Time
+	Level Level
+	// Caller is the file:line of the caller if LogLineNumber is set.
+	Caller string
+	Msg    string
+	Fields []Field
+}
+
 // Logger is the slog logging interface.
 type Logger interface {
 	Error(v string)
@@ -27,6 +116,19 @@ type Logger interface {
 	Fatal(v string)
 }
 
+// DebugLogger is implemented by Loggers with a debug level. Debug messages
+// to other Loggers are logged as info messages prefixed with "debug: ".
+type DebugLogger interface {
+	Debug(v string)
+}
+
+// Handler is implemented by Loggers that take structured entries, such as
+// JSONLog. Other Loggers receive the message with the caller prepended and
+// the fields appended as key=value pairs.
+type Handler interface {
+	Handle(e *Entry)
+}
+
 // StdLog logs to a log.Logger.
 type StdLog struct {
 	Log *log.Logger
@@ -52,6 +154,11 @@ func (s *StdLog) Warning(v string) {
 	s.Log.Println("warning:", rmNl(v))
 }
 
+// Debug logs a debug message.
+func (s *StdLog) Debug(v string) {
+	s.Log.Println("debug:", rmNl(v))
+}
+
 func rmNl(v string) string {
 	if strings.HasSuffix(v, "\n") {
 		v = v[:len(v)-1]
@@ -66,87 +173,233 @@ func Set(l Logger) {
 	logging = l
 }
 
+// Get returns the default logger for slog.
+func Get() Logger {
+	return logging
+}
+
+// Debug logs a debug message.
+func Debug(v ...interface{}) {
+	emit(LevelDebug, nil, fmt.Sprint, v...)
+}
+
+// Debugf logs a debug message.
+func Debugf(format string, v ...interface{}) {
+	emitf(LevelDebug, nil, format, v...)
+}
+
+// Debugln logs a debug message.
+func Debugln(v ...interface{}) {
+	emit(LevelDebug, nil, fmt.Sprintln, v...)
+}
+
 // Info logs an info message.
 func Info(v ...interface{}) {
-	output(logging.Info, v...)
+	emit(LevelInfo, nil, fmt.Sprint, v...)
 }
 
 // Infof logs an info message.
 func Infof(format string, v ...interface{}) {
-	outputf(logging.Info, format, v...)
+	emitf(LevelInfo, nil, format, v...)
 }
 
 // Infoln logs an info message.
 func Infoln(v ...interface{}) {
-	outputln(logging.Info, v...)
+	emit(LevelInfo, nil, fmt.Sprintln, v...)
 }
 
 // Warning logs a warning message.
 func Warning(v ...interface{}) {
-	output(logging.Warning, v...)
+	emit(LevelWarning, nil, fmt.Sprint, v...)
 }
 
 // Warningf logs a warning message.
 func Warningf(format string, v ...interface{}) {
-	outputf(logging.Warning, format, v...)
+	emitf(LevelWarning, nil, format, v...)
 }
 
 // Warningln logs a warning message.
 func Warningln(v ...interface{}) {
-	outputln(logging.Warning, v...)
+	emit(LevelWarning, nil, fmt.Sprintln, v...)
 }
 
 // Error logs an error message.
 func Error(v ...interface{}) {
-	output(logging.Error, v...)
+	emit(LevelError, nil, fmt.Sprint, v...)
 }
 
 // Errorf logs an error message.
 func Errorf(format string, v ...interface{}) {
-	outputf(logging.Error, format, v...)
+	emitf(LevelError, nil, format, v...)
 }
 
 // Errorln logs an error message.
 func Errorln(v ...interface{}) {
-	outputln(logging.Error, v...)
+	emit(LevelError, nil, fmt.Sprintln, v...)
 }
 
 // Fatal logs a fatal message and calls os.Exit(1).
 func Fatal(v ...interface{}) {
-	output(logging.Fatal, v...)
+	emit(LevelFatal, nil, fmt.Sprint, v...)
 	// Call os.Exit here just in case the logging package we are using doesn't.
 	os.Exit(1)
 }
 
 // Fatalf logs a fatal message and calls os.Exit(1).
 func Fatalf(format string, v ...interface{}) {
-	outputf(logging.Fatal, format, v...)
+	emitf(LevelFatal, nil, format, v...)
 	os.Exit(1)
 }
 
 // Fatalln logs a fatal message and calls os.Exit(1).
 func Fatalln(v ...interface{}) {
-	outputln(logging.Fatal, v...)
+	emit(LevelFatal, nil, fmt.Sprintln, v...)
 	os.Exit(1)
 }
 
-func out(f func(string), s string) {
-	if LogLineNumber {
-		if _, filename, line, ok := runtime.Caller(3); ok {
-			s = fmt.Sprintf("%s:%d: %v", filepath.Base(filename), line, s)
-		}
+// Context logs messages with a fixed set of fields, for example:
+//
+//	log := slog.With(slog.F("collector", name))
+//	log.Errorf("reading %s: %v", path, err)
+type Context struct {
+	fields []Field
+}
+
+// With returns a Context that adds fields to every message.
+func With(fields ...Field) *Context {
+	return &Context{fields: fields}
+}
+
+// With returns a Context with the fields of c and fields.
+func (c *Context) With(fields ...Field) *Context {
+	return &Context{fields: append(append([]Field{}, c.fields...), fields...)}
+}
+
+// Debug logs a debug message.
+func (c *Context) Debug(v ...interface{}) {
+	emit(LevelDebug, c.fields, fmt.Sprint, v...)
+}
+
+// Debugf logs a debug message.
+func (c *Context) Debugf(format string, v ...interface{}) {
+	emitf(LevelDebug, c.fields, format, v...)
+}
+
+// Info logs an info message.
+func (c *Context) Info(v ...interface{}) {
+	emit(LevelInfo, c.fields, fmt.Sprint, v...)
+}
+
+// Infof logs an info message.
+func (c *Context) Infof(format string, v ...interface{}) {
+	emitf(LevelInfo, c.fields, format, v...)
+}
+
+// Warning logs a warning message.
+func (c *Context) Warning(v ...interface{}) {
+	emit(LevelWarning, c.fields, fmt.Sprint, v...)
+}
+
+// Warningf logs a warning message.
+func (c *Context) Warningf(format string, v ...interface{}) {
+	emitf(LevelWarning, c.fields, format, v...)
+}
+
+// Error logs an error message.
+func (c *Context) Error(v ...interface{}) {
+	emit(LevelError, c.fields, fmt.Sprint, v...)
+}
+
+// Errorf logs an error message.
+func (c *Context) Errorf(format string, v ...interface{}) {
+	emitf(LevelError, c.fields, format, v...)
+}
+
+// Fatalf logs a fatal message and calls os.Exit(1).
+func (c *Context) Fatalf(format string, v ...interface{}) {
+	emitf(LevelFatal, c.fields, format, v...)
+	os.Exit(1)
+}
+
+// callerDepth is the number of stack frames between out and the caller of
+// an exported logging function.
+const callerDepth = 3
+
+func emit(l Level, fields []Field, sprint func(...interface{}) string, v ...interface{}) {
+	if Enabled(l) {
+		out(l, fields, sprint(v...))
+	}
+}
+
+func emitf(l Level, fields []Field, format string, v ...interface{}) {
+	if Enabled(l) {
+		out(l, fields, fmt.Sprintf(format, v...))
 	}
-	f(s)
 }
 
-func output(f func(string), v ...interface{}) {
-	out(f, fmt.Sprint(v...))
+func out(l Level, fields []Field, s string) {
+	e := &Entry{
+		Time:   time.Now(),
+		Level:  l,
+		Msg:    rmNl(s),
+		Fields: fields,
+	}
+	if LogLineNumber {
+		if _, filename, line, ok := runtime.Caller(callerDepth); ok {
+			e.Caller = fmt.Sprintf("%s:%d", filepath.Base(filename), line)
+		}
+	}
+	logger := logging
+	if h, ok := logger.(Handler); ok {
+		h.Handle(e)
+		return
+	}
+	s = e.text()
+	switch l {
+	case LevelDebug:
+		if d, ok := logger.(DebugLogger); ok {
+			d.Debug(s)
+		} else {
+			logger.Info("debug: " + s)
+		}
+	case LevelInfo:
+		logger.Info(s)
+	case LevelWarning:
+		logger.Warning(s)
+	case LevelError:
+		logger.Error(s)
+	default:
+		logger.Fatal(s)
+	}
 }
 
-func outputf(f func(string), format string, v ...interface{}) {
-	out(f, fmt.Sprintf(format, v...))
+// text formats e without its time and level as "caller: msg k=v ...".
+// Values containing spaces, quotes or = are quoted.
+func (e *Entry) text() string {
+	b := &bytes.Buffer{}
+	if e.Caller != "" {
+		b.WriteString(e.Caller)
+		b.WriteString(": ")
+	}
+	b.WriteString(e.Msg)
+	for _, f := range e.Fields {
+		v := fieldString(f.Value)
+		if v == "" || strings.ContainsAny(v, " \"=") {
+			v = fmt.Sprintf("%q", v)
+		}
+		fmt.Fprintf(b, " %s=%s", f.Key, v)
+	}
+	return b.String()
 }
 
-func outputln(f func(string), v ...interface{}) {
-	out(f, fmt.Sprintln(v...))
+func fieldString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	}
+	return fmt.Sprint(v)
 }
@@ -0,0 +1,98 @@
+package slog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type recorder []string
+
+func (r *recorder) Error(v string)   { *r = append(*r, "error: "+v) }
+func (r *recorder) Info(v string)    { *r = append(*r, "info: "+v) }
+func (r *recorder) Warning(v string) { *r = append(*r, "warning: "+v) }
+func (r *recorder) Fatal(v string)   { *r = append(*r, "fatal: "+v) }
+
+func capture(t *testing.T, l Logger) {
+	prev, prevLevel := Get(), GetLevel()
+	Set(l)
+	t.Cleanup(func() {
+		Set(prev)
+		SetLevel(prevLevel)
+	})
+}
+
+func TestLevels(t *testing.T) {
+	r := &recorder{}
+	capture(t, r)
+	LogLineNumber = false
+	defer func() { LogLineNumber = true }()
+
+	Debug("hidden")
+	Info("shown")
+	SetLevel(LevelDebug)
+	Debugf("now %s", "shown")
+	SetLevel(LevelError)
+	Warningln("hidden")
+	Error("shown")
+	expected := []string{"info: shown", "info: debug: now shown", "error: shown"}
+	if strings.Join(*r, "|") != strings.Join(expected, "|") {
+		t.Errorf("got %q, expected %q", *r, expected)
+	}
+}
+
+func TestFields(t *testing.T) {
+	r := &recorder{}
+	capture(t, r)
+	log := With(F("collector", "c_redis"))
+	log.With(Err(errors.New("connection refused")), F("port", 6379)).Errorf("collection %s", "failed")
+	if len(*r) != 1 {
+		t.Fatalf("got %q", *r)
+	}
+	got := (*r)[0]
+	if !strings.HasPrefix(got, "error: slog_test.go:") || !strings.HasSuffix(got, `: collection failed collector=c_redis err="connection refused" port=6379`) {
+		t.Errorf("got %q", got)
+	}
+	// The fields of log are not changed by With.
+	log.Info("x")
+	if !strings.HasSuffix((*r)[1], "x collector=c_redis") {
+		t.Errorf("got %q", (*r)[1])
+	}
+}
+
+func TestJSONLog(t *testing.T) {
+	b := &bytes.Buffer{}
+	capture(t, &JSONLog{W: b})
+	With(F("collector", "c_redis"), F("n", 2)).Warningf("a %q", "b")
+	Infoln("second")
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %q", b.String())
+	}
+	var e map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &e); err != nil {
+		t.Fatal(err)
+	}
+	if e["level"] != "warning" || e["msg"] != `a "b"` || e["collector"] != "c_redis" || e["n"] != 2.0 || !strings.HasPrefix(e["caller"].(string), "slog_test.go:") {
+		t.Errorf("got %v", e)
+	}
+	if !strings.Contains(lines[1], `"msg":"second"}`) {
+		t.Errorf("got %s", lines[1])
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for _, l := range []Level{LevelDebug, LevelInfo, LevelWarning, LevelError, LevelFatal} {
+		if p, err := ParseLevel(strings.ToUpper(l.String())); err != nil || p != l {
+			t.Errorf("%v: got %v, %v", l, p, err)
+		}
+	}
+	if l, err := ParseLevel("warn"); err != nil || l != LevelWarning {
+		t.Errorf("warn: got %v, %v", l, err)
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("expected error")
+	}
+}
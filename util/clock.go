@@ -0,0 +1,102 @@
+package util
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// A Clock tells the time and waits for it to pass. Code that schedules work
+// or stamps data takes the time from a Clock, so that tests can move it by
+// hand with a FakeClock.
+type Clock interface {
+	Now() time.Time
+	// After waits for d to pass and then sends the current time on the
+	// returned channel, as time.After.
+	After(d time.Duration) <-chan time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// SystemClock is the real time of the time package.
+var SystemClock Clock = systemClock{}
+
+// A FakeClock is a Clock that only moves when told to.
+type FakeClock struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	waiters []fakeWaiter
+}
+
+type fakeWaiter struct {
+	until time.Time
+	c     chan time.Time
+}
+
+// NewFakeClock returns a FakeClock at t.
+func NewFakeClock(t time.Time) *FakeClock {
+	c := &FakeClock{now: t}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, fakeWaiter{c.now.Add(d), ch})
+	c.cond.Broadcast()
+	return ch
+}
+
+// Advance moves the clock forward by d and fires the After channels that
+// are due, in the order of their deadlines.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	sort.SliceStable(c.waiters, func(i, j int) bool {
+		return c.waiters[i].until.Before(c.waiters[j].until)
+	})
+	n := 0
+	for _, w := range c.waiters {
+		if w.until.After(c.now) {
+			c.waiters[n] = w
+			n++
+			continue
+		}
+		w.c <- c.now
+	}
+	c.waiters = c.waiters[:n]
+}
+
+// Waiters returns the number of After channels that have not fired.
+func (c *FakeClock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
+
+// BlockUntil waits until n After channels are pending, such as when the
+// goroutines under test have gone to sleep and the clock can be advanced.
+func (c *FakeClock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.waiters) < n {
+		c.cond.Wait()
+	}
+}
@@ -0,0 +1,55 @@
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC)
+	c := NewFakeClock(start)
+	late := c.After(time.Minute)
+	early := c.After(time.Second)
+	select {
+	case <-c.After(0):
+	default:
+		t.Error("After(0) did not fire at once")
+	}
+	if n := c.Waiters(); n != 2 {
+		t.Fatalf("got %d waiters, expected 2", n)
+	}
+	c.Advance(time.Second * 30)
+	select {
+	case now := <-early:
+		if !now.Equal(start.Add(time.Second * 30)) {
+			t.Errorf("early: got %v", now)
+		}
+	default:
+		t.Error("early did not fire")
+	}
+	select {
+	case <-late:
+		t.Error("late fired before its deadline")
+	default:
+	}
+	c.Advance(time.Second * 30)
+	if now := <-late; !now.Equal(start.Add(time.Minute)) {
+		t.Errorf("late: got %v", now)
+	}
+	if !c.Now().Equal(start.Add(time.Minute)) {
+		t.Errorf("now: got %v", c.Now())
+	}
+}
+
+func TestFakeClockBlockUntil(t *testing.T) {
+	c := NewFakeClock(time.Unix(0, 0))
+	done := make(chan time.Time)
+	go func() {
+		done <- <-c.After(time.Hour)
+	}()
+	c.BlockUntil(1)
+	c.Advance(time.Hour)
+	if now := <-done; now.Unix() != 3600 {
+		t.Errorf("got %v", now)
+	}
+}
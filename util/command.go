@@ -3,9 +3,14 @@ package util
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"errors"
+	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/oliveagle/go-collectors/slog"
@@ -19,12 +24,69 @@ var (
 
 	// Debug enables debug logging.
 	Debug = false
+
+	// KillDelay is the time between interrupting the process group of a
+	// command whose context is done and killing it, for the Context
+	// functions.
+	KillDelay = time.Second * 5
+	// MaxStderr is the number of bytes of stderr kept for ExitErrors.
+	MaxStderr = 4096
 )
 
+// ExitError is returned if a program exits unsuccessfully.
+type ExitError struct {
+	Name string
+	// ExitCode is the exit status of the program, or -1 if it was
+	// terminated by a signal.
+	ExitCode int
+	// Stderr holds up to MaxStderr bytes of the program's stderr.
+	Stderr []byte
+	Err    *exec.ExitError
+}
+
+func (e *ExitError) Error() string {
+	s := fmt.Sprintf("%s: %v", e.Name, e.Err)
+	if stderr := strings.TrimSpace(string(e.Stderr)); stderr != "" {
+		s += ": " + stderr
+	}
+	return s
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
 // Command executes the named program with the given arguments. If it does not
-// exit within timeout, it is sent SIGINT (if supported by Go). After
-// another timeout, it is killed.
+// exit within timeout, its process group is sent SIGINT (if supported by
+// Go). After another timeout, the group is killed.
 func Command(timeout time.Duration, name string, arg ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return command(ctx, timeout, name, arg...)
+}
+
+// CommandContext is like Command, but the process group of the program is
+// interrupted once ctx is done and killed KillDelay later.
+func CommandContext(ctx context.Context, name string, arg ...string) ([]byte, error) {
+	return command(ctx, KillDelay, name, arg...)
+}
+
+func command(ctx context.Context, killDelay time.Duration, name string, arg ...string) ([]byte, error) {
+	c, err := newCmd(name, arg...)
+	if err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	c.Stdout = &b
+	wait, err := start(ctx, killDelay, c)
+	if err != nil {
+		return nil, err
+	}
+	err = wait()
+	return b.Bytes(), err
+}
+
+func newCmd(name string, arg ...string) (*exec.Cmd, error) {
 	if _, err := exec.LookPath(name); err != nil {
 		return nil, ErrPath
 	}
@@ -32,26 +94,175 @@ func Command(timeout time.Duration, name string, arg ...string) ([]byte, error)
 		slog.Infof("executing command: %v %v", name, arg)
 	}
 	c := exec.Command(name, arg...)
-	var b bytes.Buffer
-	c.Stdout = &b
-	done := make(chan error, 1)
+	c.Stderr = &limitedBuffer{max: MaxStderr}
+	setProcessGroup(c)
+	return c, nil
+}
+
+// start starts c. Once ctx is done, the process group of c is interrupted
+// and killed killDelay later. The group is only signaled while the exited
+// leader is not reaped, since its id may be reused afterwards. Where the
+// exit of the leader can be awaited without reaping it, the rest of the
+// group, such as children that ignore the interrupt, is killed as soon as
+// the leader exits. The returned function waits for c and returns
+// ErrTimeout if ctx hit its deadline, ctx.Err() if ctx was canceled and an
+// *ExitError if c failed.
+func start(ctx context.Context, killDelay time.Duration, c *exec.Cmd) (wait func() error, err error) {
+	outputs, err := pipeOutputs(c)
+	if err != nil {
+		return nil, err
+	}
+	err = c.Start()
+	for _, p := range outputs {
+		*p.out = p.dst
+		p.w.Close()
+		if err != nil {
+			p.r.Close()
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	var (
+		mu     sync.Mutex
+		reaped bool
+	)
+	signal := func(f func(*os.Process)) {
+		mu.Lock()
+		defer mu.Unlock()
+		if !reaped {
+			f(c.Process)
+		}
+	}
+	exited := make(chan struct{})
 	go func() {
-		done <- c.Run()
+		select {
+		case <-exited:
+			return
+		case <-ctx.Done():
+		}
+		signal(interruptGroup)
+		t := time.NewTimer(killDelay)
+		defer t.Stop()
+		select {
+		case <-exited:
+		case <-t.C:
+			signal(killGroup)
+		}
 	}()
-	interrupt := time.After(timeout)
-	kill := time.After(timeout * 2)
-	for {
+	return func() error {
+		var err error
+		if waitExit(c.Process) {
+			mu.Lock()
+			if ctx.Err() != nil {
+				killGroup(c.Process)
+			}
+			reaped = true
+			mu.Unlock()
+			err = c.Wait()
+		} else {
+			err = c.Wait()
+			mu.Lock()
+			reaped = true
+			mu.Unlock()
+		}
+		close(exited)
+		waitOutputs(outputs, killDelay)
+		switch ctx.Err() {
+		case nil:
+		case context.DeadlineExceeded:
+			return ErrTimeout
+		default:
+			return ctx.Err()
+		}
+		var ee *exec.ExitError
+		if errors.As(err, &ee) {
+			return &ExitError{
+				Name:     c.Args[0],
+				ExitCode: ee.ExitCode(),
+				Stderr:   c.Stderr.(*limitedBuffer).Bytes(),
+				Err:      ee,
+			}
+		}
+		return err
+	}, nil
+}
+
+// An outputPipe copies an output of a command to its writer dst.
+type outputPipe struct {
+	out  *io.Writer
+	dst  io.Writer
+	r, w *os.File
+	done chan struct{}
+}
+
+// pipeOutputs replaces the stdout and stderr writers of c that are not files
+// with pipes copied to them, until c is started. Grandchildren may keep the pipes open after c
+// exits, and Wait would wait for them if it copied the outputs itself.
+func pipeOutputs(c *exec.Cmd) ([]*outputPipe, error) {
+	var outputs []*outputPipe
+	for _, out := range []*io.Writer{&c.Stdout, &c.Stderr} {
+		if _, ok := (*out).(*os.File); ok || *out == nil {
+			continue
+		}
+		r, w, err := os.Pipe()
+		if err != nil {
+			for _, p := range outputs {
+				p.r.Close()
+				p.w.Close()
+			}
+			return nil, err
+		}
+		p := &outputPipe{out: out, dst: *out, r: r, w: w, done: make(chan struct{})}
+		go func() {
+			io.Copy(p.dst, p.r)
+			close(p.done)
+		}()
+		*out = w
+		outputs = append(outputs, p)
+	}
+	return outputs, nil
+}
+
+// waitOutputs waits up to delay for the outputs to be closed by the
+// processes writing them, and closes them afterwards.
+func waitOutputs(outputs []*outputPipe, delay time.Duration) {
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	for _, p := range outputs {
 		select {
-		case err := <-done:
-			return b.Bytes(), err
-		case <-interrupt:
-			c.Process.Signal(os.Interrupt)
-		case <-kill:
-			// todo: figure out if this can leave the done chan hanging open
-			c.Process.Kill()
-			return nil, ErrTimeout
+		case <-p.done:
+		case <-t.C:
+			for _, p := range outputs {
+				p.r.Close()
+			}
+		}
+		<-p.done
+		p.r.Close()
+	}
+}
+
+// limitedBuffer keeps the first max bytes written to it and discards the
+// rest. The buffer is not embedded, so that io.Copy cannot bypass Write
+// through its ReadFrom method.
+type limitedBuffer struct {
+	buf bytes.Buffer
+	max int
+}
+
+func (l *limitedBuffer) Write(p []byte) (int, error) {
+	if n := l.max - l.buf.Len(); n > 0 {
+		if len(p) > n {
+			l.buf.Write(p[:n])
+		} else {
+			l.buf.Write(p)
 		}
 	}
+	return len(p), nil
+}
+
+func (l *limitedBuffer) Bytes() []byte {
+	return l.buf.Bytes()
 }
 
 // ReadCommand runs command name with args and calls line for each line from its
@@ -63,18 +274,54 @@ func ReadCommand(line func(string) error, name string, arg ...string) error {
 
 // ReadCommandTimeout is the same as ReadCommand with a specifiable timeout.
 func ReadCommandTimeout(timeout time.Duration, line func(string) error, name string, arg ...string) error {
-	b, err := Command(timeout, name, arg...)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return readCommand(ctx, timeout, line, name, arg...)
+}
+
+// ReadCommandContext runs command name with args and calls line for each
+// line of its stdout as it is read, so that its output is never held in
+// memory as a whole. The process group of the command is interrupted once
+// ctx is done and killed KillDelay later. If line returns an error, the
+// command is stopped the same way and the error returned.
+func ReadCommandContext(ctx context.Context, line func(string) error, name string, arg ...string) error {
+	return readCommand(ctx, KillDelay, line, name, arg...)
+}
+
+func readCommand(ctx context.Context, killDelay time.Duration, line func(string) error, name string, arg ...string) error {
+	c, err := newCmd(name, arg...)
+	if err != nil {
+		return err
+	}
+	stdout, err := c.StdoutPipe()
 	if err != nil {
 		return err
 	}
-	scanner := bufio.NewScanner(bytes.NewBuffer(b))
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	wait, err := start(ctx, killDelay, c)
+	if err != nil {
+		return err
+	}
+	var lerr error
+	scanner := bufio.NewScanner(stdout)
 	for scanner.Scan() {
-		if err := line(scanner.Text()); err != nil {
-			return err
+		if lerr = line(scanner.Text()); lerr != nil {
+			break
 		}
 	}
 	if err := scanner.Err(); err != nil {
+		// As when the output was read as a whole, an unreadable line is
+		// logged but does not fail the command; the rest is skipped.
 		slog.Infof("%v: %v\n", name, err)
+		cancel()
+		wait()
+		return nil
+	}
+	if lerr != nil {
+		cancel()
+		wait()
+		return lerr
 	}
-	return nil
+	return wait()
 }
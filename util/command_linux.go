@@ -0,0 +1,21 @@
+package util
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const pPID = 1
+
+// waitExit waits for p to exit without reaping it, so that the pid of p, and
+// with it the id of its process group, is not reused until p is waited for.
+func waitExit(p *os.Process) bool {
+	var info [128]byte
+	for {
+		_, _, e := syscall.Syscall6(syscall.SYS_WAITID, pPID, uintptr(p.Pid), uintptr(unsafe.Pointer(&info[0])), syscall.WEXITED|syscall.WNOWAIT, 0, 0)
+		if e != syscall.EINTR {
+			return e == 0
+		}
+	}
+}
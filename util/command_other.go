@@ -0,0 +1,11 @@
+//go:build !linux
+// +build !linux
+
+package util
+
+import "os"
+
+// waitExit reports that the exit of p cannot be awaited without reaping p.
+func waitExit(p *os.Process) bool {
+	return false
+}
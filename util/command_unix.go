@@ -0,0 +1,23 @@
+// +build !windows
+
+package util
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup makes c the leader of a new process group, so that it can
+// be signaled together with its children.
+func setProcessGroup(c *exec.Cmd) {
+	c.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+func interruptGroup(p *os.Process) {
+	syscall.Kill(-p.Pid, syscall.SIGINT)
+}
+
+func killGroup(p *os.Process) {
+	syscall.Kill(-p.Pid, syscall.SIGKILL)
+}
@@ -0,0 +1,137 @@
+// +build !windows
+
+package util
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCommand(t *testing.T) {
+	b, err := Command(time.Second*5, "sh", "-c", "echo out; echo err >&2")
+	if err != nil || string(b) != "out\n" {
+		t.Fatalf("got %q, %v", b, err)
+	}
+	_, err = Command(time.Second*5, "sh", "-c", "echo failed >&2; exit 3")
+	var ee *ExitError
+	if !errors.As(err, &ee) || ee.ExitCode != 3 || string(ee.Stderr) != "failed\n" {
+		t.Fatalf("got %#v", err)
+	}
+	if !strings.HasSuffix(err.Error(), "exit status 3: failed") {
+		t.Errorf("got %q", err)
+	}
+	if _, err := Command(time.Second, "no-such-program-x"); err != ErrPath {
+		t.Errorf("got %v, expected ErrPath", err)
+	}
+}
+
+func TestCommandStderrLimit(t *testing.T) {
+	defer func(m int) { MaxStderr = m }(MaxStderr)
+	MaxStderr = 10
+	_, err := Command(time.Second*5, "sh", "-c", "printf 0123456789abcdef >&2; exit 1")
+	var ee *ExitError
+	if !errors.As(err, &ee) || string(ee.Stderr) != "0123456789" {
+		t.Fatalf("got %#v", err)
+	}
+}
+
+// TestCommandKillsGroup checks that a background child of a timed out
+// script, which ignores SIGINT, is killed with the script.
+func TestCommandKillsGroup(t *testing.T) {
+	var pid int
+	err := ReadCommandTimeout(time.Millisecond*300, func(line string) error {
+		pid, _ = strconv.Atoi(line)
+		return nil
+	}, "sh", "-c", "sleep 30 & echo $!; wait")
+	if err != ErrTimeout {
+		t.Fatalf("got %v, expected ErrTimeout", err)
+	}
+	if pid == 0 {
+		t.Fatal("no pid read")
+	}
+	waitExited(t, pid)
+}
+
+// waitExited waits for the process pid to exit.
+func waitExited(t *testing.T, pid int) {
+	for deadline := time.Now().Add(time.Second * 5); ; {
+		b, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+		// Orphans may be left as zombies if nothing reaps them.
+		if err != nil || strings.Contains(string(b), ") Z ") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("grandchild %d still running: %s", pid, b)
+		}
+		time.Sleep(time.Millisecond * 50)
+	}
+}
+
+// TestCommandKillsGroupIgnoringInterrupt checks that a grandchild that
+// ignores SIGINT is killed even though the script exits before the kill.
+// The script exits on SIGINT, or soon after if SIGINT was ignored when the
+// test started.
+func TestCommandKillsGroupIgnoringInterrupt(t *testing.T) {
+	var pid int
+	err := ReadCommandTimeout(time.Millisecond*300, func(line string) error {
+		pid, _ = strconv.Atoi(line)
+		return nil
+	}, "sh", "-c", `(trap "" INT; exec sleep 30 >/dev/null 2>&1) & echo $!; sleep 0.4`)
+	if err != ErrTimeout {
+		t.Fatalf("got %v, expected ErrTimeout", err)
+	}
+	if pid == 0 {
+		t.Fatal("no pid read")
+	}
+	waitExited(t, pid)
+}
+
+// TestCommandGrandchildKeepsOutput checks that a command returns KillDelay
+// after it exited although a background child keeps its stdout open.
+func TestCommandGrandchildKeepsOutput(t *testing.T) {
+	defer func(d time.Duration) { KillDelay = d }(KillDelay)
+	KillDelay = time.Millisecond * 300
+	start := time.Now()
+	b, err := CommandContext(context.Background(), "sh", "-c", "sleep 30 & echo $!")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d > time.Second*2 {
+		t.Errorf("took %v", d)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil {
+		t.Fatalf("got %q: %v", b, err)
+	}
+	if p, err := os.FindProcess(pid); err == nil {
+		p.Kill()
+	}
+}
+
+func TestReadCommandContext(t *testing.T) {
+	stop := errors.New("stop")
+	n := 0
+	start := time.Now()
+	err := ReadCommandContext(context.Background(), func(line string) error {
+		if n++; n == 3 {
+			return stop
+		}
+		return nil
+	}, "sh", "-c", "while :; do echo y; done")
+	if err != stop || n != 3 {
+		t.Errorf("got %v after %d lines", err, n)
+	}
+	if d := time.Since(start); d > KillDelay {
+		t.Errorf("took %v", d)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := ReadCommandContext(ctx, func(string) error { return nil }, "sleep", "10"); err != context.Canceled {
+		t.Errorf("got %v, expected context.Canceled", err)
+	}
+}
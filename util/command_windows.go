@@ -0,0 +1,24 @@
+package util
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+func setProcessGroup(c *exec.Cmd) {
+	c.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
+
+// interruptGroup kills the process tree of p, since Windows cannot send
+// console interrupts to other process groups.
+func interruptGroup(p *os.Process) {
+	killGroup(p)
+}
+
+func killGroup(p *os.Process) {
+	if err := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(p.Pid)).Run(); err != nil {
+		p.Kill()
+	}
+}
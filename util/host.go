@@ -0,0 +1,148 @@
+package util
+
+import (
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// ErrNoHost is returned by a HostProvider that cannot determine the host
+// identity.
+var ErrNoHost = errors.New("host identity not available")
+
+// HostEnv is the environment variable that overrides the host identity in
+// the default Hosts provider, for containers whose hostname is meaningless.
+const HostEnv = "COLLECTORS_HOST"
+
+// A HostProvider determines the identity of the local host, which is sent as
+// the host tag of datapoints.
+type HostProvider interface {
+	Host() (string, error)
+}
+
+// HostFunc adapts a function to a HostProvider.
+type HostFunc func() (string, error)
+
+func (f HostFunc) Host() (string, error) {
+	return f()
+}
+
+// StaticHost provides name, such as a host configured explicitly. An empty
+// name provides nothing.
+func StaticHost(name string) HostProvider {
+	return HostFunc(func() (string, error) {
+		if name == "" {
+			return "", ErrNoHost
+		}
+		return name, nil
+	})
+}
+
+// EnvHost provides the value of the environment variable key.
+func EnvHost(key string) HostProvider {
+	return HostFunc(func() (string, error) {
+		if h := os.Getenv(key); h != "" {
+			return h, nil
+		}
+		return "", ErrNoHost
+	})
+}
+
+// MachineIDHost provides the machine ID read from path, usually
+// /etc/machine-id, which survives hostname changes.
+func MachineIDHost(path string) HostProvider {
+	return HostFunc(func() (string, error) {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		if id := strings.TrimSpace(string(b)); id != "" {
+			return id, nil
+		}
+		return "", ErrNoHost
+	})
+}
+
+// OSHost provides the hostname reported by the kernel.
+var OSHost HostProvider = HostFunc(os.Hostname)
+
+// FQDNHost provides the fully qualified domain name of the host, found by a
+// reverse lookup of its primary address. Set FullHostname to keep the domain.
+var FQDNHost HostProvider = HostFunc(fqdn)
+
+func fqdn() (string, error) {
+	// Connecting a UDP socket sends nothing, but selects the address of the
+	// interface with the default route.
+	c, err := net.Dial("udp", "192.0.2.1:9")
+	if err != nil {
+		return "", err
+	}
+	ip := c.LocalAddr().(*net.UDPAddr).IP
+	c.Close()
+	names, err := net.LookupAddr(ip.String())
+	if err != nil {
+		return "", err
+	}
+	if len(names) == 0 {
+		return "", ErrNoHost
+	}
+	return strings.TrimSuffix(names[0], "."), nil
+}
+
+// FirstHost provides the host of the first of ps that provides one. If none
+// does, the error of the last is returned.
+func FirstHost(ps ...HostProvider) HostProvider {
+	return HostFunc(func() (string, error) {
+		err := ErrNoHost
+		for _, p := range ps {
+			var h string
+			if h, err = p.Host(); err == nil && h != "" {
+				return h, nil
+			}
+		}
+		return "", err
+	})
+}
+
+var (
+	// Hosts determines the host identity when Set is called. It defaults to
+	// the HostEnv environment variable, falling back to the hostname. Run
+	// Set() after changing it.
+	Hosts HostProvider = FirstHost(EnvHost(HostEnv), OSHost)
+
+	// Hostname is the host identity resolved by the last call to Set.
+	//
+	// Deprecated: Use Host, which is safe to call while Set runs.
+	Hostname string
+
+	host atomic.Value
+)
+
+// Host returns the host identity resolved by the last call to Set.
+func Host() string {
+	h, _ := host.Load().(string)
+	return h
+}
+
+// Set resolves the host identity with Hosts and cleans it based on the
+// current FullHostname setting. It is run on startup and should be run again
+// when the configuration is reloaded. If Hosts fails, the previous identity
+// is kept, or "unknown" if there is none, and the error is returned.
+func Set() error {
+	h, err := Hosts.Host()
+	if err == nil && h == "" {
+		err = ErrNoHost
+	}
+	if err != nil {
+		if Host() == "" {
+			host.Store("unknown")
+			Hostname = Host()
+		}
+		return err
+	}
+	host.Store(Clean(h))
+	Hostname = Host()
+	return nil
+}
@@ -0,0 +1,59 @@
+package util
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHostProviders(t *testing.T) {
+	dir := t.TempDir()
+	id := filepath.Join(dir, "machine-id")
+	if err := os.WriteFile(id, []byte("0f3a5c\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TEST_HOST", "Container-1")
+	fail := HostFunc(func() (string, error) { return "", errors.New("fail") })
+	tests := []struct {
+		p    HostProvider
+		host string
+		err  bool
+	}{
+		{StaticHost("web01"), "web01", false},
+		{StaticHost(""), "", true},
+		{EnvHost("TEST_HOST"), "Container-1", false},
+		{EnvHost("TEST_HOST_UNSET"), "", true},
+		{MachineIDHost(id), "0f3a5c", false},
+		{MachineIDHost(filepath.Join(dir, "missing")), "", true},
+		{FirstHost(StaticHost(""), fail, EnvHost("TEST_HOST"), StaticHost("web01")), "Container-1", false},
+		{FirstHost(StaticHost(""), fail), "", true},
+	}
+	for i, test := range tests {
+		host, err := test.p.Host()
+		if host != test.host || (err != nil) != test.err {
+			t.Errorf("%d: got %q, %v", i, host, err)
+		}
+	}
+}
+
+func TestSet(t *testing.T) {
+	defer func(p HostProvider, full bool) {
+		Hosts, FullHostname = p, full
+		Set()
+	}(Hosts, FullHostname)
+
+	Hosts = StaticHost("Web01.example.com")
+	if err := Set(); err != nil || Host() != "web01" || Hostname != "web01" {
+		t.Errorf("got %q, %q, %v", Host(), Hostname, err)
+	}
+	FullHostname = true
+	if err := Set(); err != nil || Host() != "web01.example.com" {
+		t.Errorf("got %q, %v", Host(), err)
+	}
+	// A failed reload keeps the previous identity.
+	Hosts = StaticHost("")
+	if err := Set(); err != ErrNoHost || Host() != "web01.example.com" {
+		t.Errorf("got %q, %v", Host(), err)
+	}
+}
@@ -0,0 +1,57 @@
+package util
+
+import (
+	"bufio"
+	"context"
+	"strings"
+)
+
+// A Runner runs external programs. Collectors run programs through a Runner,
+// so that tests can replay recorded outputs instead.
+type Runner interface {
+	// ReadCommand runs name with args and calls line for each line of its
+	// stdout, as ReadCommandContext.
+	ReadCommand(ctx context.Context, line func(string) error, name string, arg ...string) error
+}
+
+// ExecRunner runs programs with ReadCommandContext.
+type ExecRunner struct{}
+
+func (ExecRunner) ReadCommand(ctx context.Context, line func(string) error, name string, arg ...string) error {
+	return ReadCommandContext(ctx, line, name, arg...)
+}
+
+// FixtureRunner replays recorded outputs of programs. It is keyed by argv,
+// the program name and its arguments joined by single spaces, such as
+// "df -lP --block-size 1". Programs without a recorded output are not in
+// the PATH.
+type FixtureRunner map[string]CommandOutput
+
+// CommandOutput is a recorded output of a program.
+type CommandOutput struct {
+	Stdout string
+	// Err is returned after all lines of Stdout were read.
+	Err error
+}
+
+// Argv returns the key of name and args in a FixtureRunner.
+func Argv(name string, arg ...string) string {
+	return strings.Join(append([]string{name}, arg...), " ")
+}
+
+func (r FixtureRunner) ReadCommand(ctx context.Context, line func(string) error, name string, arg ...string) error {
+	out, ok := r[Argv(name, arg...)]
+	if !ok {
+		return ErrPath
+	}
+	scanner := bufio.NewScanner(strings.NewReader(out.Stdout))
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := line(scanner.Text()); err != nil {
+			return err
+		}
+	}
+	return out.Err
+}
@@ -0,0 +1,33 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestFixtureRunner(t *testing.T) {
+	failed := errors.New("exit status 1")
+	r := FixtureRunner{
+		Argv("df", "-liP"):  {Stdout: "a\nb\nc\n"},
+		Argv("ntpq", "-pn"): {Stdout: "a\n", Err: failed},
+	}
+	var lines []string
+	err := r.ReadCommand(context.Background(), func(line string) error {
+		lines = append(lines, line)
+		return nil
+	}, "df", "-liP")
+	if err != nil || len(lines) != 3 {
+		t.Errorf("got %q, %v", lines, err)
+	}
+	stop := errors.New("stop")
+	if err := r.ReadCommand(context.Background(), func(string) error { return stop }, "df", "-liP"); err != stop {
+		t.Errorf("got %v, expected stop", err)
+	}
+	if err := r.ReadCommand(context.Background(), func(string) error { return nil }, "ntpq", "-pn"); err != failed {
+		t.Errorf("got %v, expected %v", err, failed)
+	}
+	if err := r.ReadCommand(context.Background(), func(string) error { return nil }, "df"); err != ErrPath {
+		t.Errorf("got %v, expected ErrPath", err)
+	}
+}
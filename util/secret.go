@@ -0,0 +1,169 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// SecretTimeout limits the helper commands of exec: secrets.
+	SecretTimeout = time.Second * 10
+	// SecretTTL is how long the value of an exec: secret is kept before
+	// its helper command is run again.
+	SecretTTL = time.Minute * 5
+	// SecretClock tells the time of SecretTTL.
+	SecretClock = SystemClock
+)
+
+// A Secret is a credential, such as a password or an SNMP community. It is
+// either the credential itself or a reference to where it is kept:
+//
+//	env:NAME      the environment variable NAME
+//	file:PATH     the content of the file PATH, without trailing newlines;
+//	              the file must not be accessible by group or others
+//	exec:COMMAND  the first line of the output of COMMAND, a program and
+//	              its arguments separated by spaces
+//
+// Environment variables and files are read each time the secret is used, so
+// that changed secrets are picked up. The values of helper commands are
+// kept for SecretTTL, since the secret may be used for every request of a
+// collector. A Secret prints and marshals as its
+// reference, or as Redacted if it is a credential, so that credentials
+// do not end up in logs, collector names and configuration dumps.
+type Secret string
+
+// Redacted is printed instead of credentials.
+const Redacted = "<redacted>"
+
+var secretSchemes = []string{"env:", "file:", "exec:"}
+
+// IsRef reports whether s is a reference rather than the credential itself.
+func (s Secret) IsRef() bool {
+	for _, p := range secretSchemes {
+		if strings.HasPrefix(string(s), p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Value returns the credential of s.
+func (s Secret) Value() (string, error) {
+	v := string(s)
+	switch {
+	case strings.HasPrefix(v, "env:"):
+		name := strings.TrimPrefix(v, "env:")
+		if e, ok := os.LookupEnv(name); ok {
+			return e, nil
+		}
+		return "", fmt.Errorf("secret %s: not set", s)
+	case strings.HasPrefix(v, "file:"):
+		b, err := readSecretFile(strings.TrimPrefix(v, "file:"))
+		if err != nil {
+			return "", fmt.Errorf("secret %s: %v", s, err)
+		}
+		return strings.TrimRight(string(b), "\r\n"), nil
+	case strings.HasPrefix(v, "exec:"):
+		return secretExecs.value(s)
+	}
+	return v, nil
+}
+
+// secretExecs caches the values of exec: secrets.
+var secretExecs = &secretCache{entries: make(map[Secret]*secretEntry)}
+
+type secretCache struct {
+	sync.Mutex
+	entries map[Secret]*secretEntry
+}
+
+// A secretEntry is locked while its command runs, so that concurrent uses
+// of a secret wait for the same run.
+type secretEntry struct {
+	sync.Mutex
+	value   string
+	expires time.Time
+}
+
+func (c *secretCache) value(s Secret) (string, error) {
+	c.Lock()
+	e := c.entries[s]
+	if e == nil {
+		e = &secretEntry{}
+		c.entries[s] = e
+	}
+	c.Unlock()
+	e.Lock()
+	defer e.Unlock()
+	if now := SecretClock.Now(); now.Before(e.expires) {
+		return e.value, nil
+	}
+	v, err := execSecret(s)
+	if err != nil {
+		return "", err
+	}
+	e.value, e.expires = v, SecretClock.Now().Add(SecretTTL)
+	return v, nil
+}
+
+// execSecret runs the helper command of s and returns the first line of its
+// output.
+func execSecret(s Secret) (string, error) {
+	args := strings.Fields(strings.TrimPrefix(string(s), "exec:"))
+	if len(args) == 0 {
+		return "", fmt.Errorf("secret %s: no command", s)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), SecretTimeout)
+	defer cancel()
+	b, err := CommandContext(ctx, args[0], args[1:]...)
+	if err != nil {
+		return "", fmt.Errorf("secret %s: %v", s, err)
+	}
+	line := strings.SplitN(string(b), "\n", 2)[0]
+	if line = strings.TrimRight(line, "\r"); line == "" {
+		return "", fmt.Errorf("secret %s: no output", s)
+	}
+	return line, nil
+}
+
+// readSecretFile reads the file name, which must only be accessible by its
+// owner on systems with Unix permissions.
+func readSecretFile(name string) ([]byte, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if perm := fi.Mode().Perm(); runtime.GOOS != "windows" && perm&0077 != 0 {
+		return nil, fmt.Errorf("permissions %#o are open to group or others", perm)
+	}
+	return io.ReadAll(f)
+}
+
+// String returns the reference of s, or Redacted if s is a credential.
+func (s Secret) String() string {
+	if s == "" || s.IsRef() {
+		return string(s)
+	}
+	return Redacted
+}
+
+// GoString redacts s in %#v.
+func (s Secret) GoString() string {
+	return fmt.Sprintf("util.Secret(%q)", s.String())
+}
+
+// MarshalText redacts s in configuration dumps, such as JSON or YAML.
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
@@ -0,0 +1,136 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSecretValue(t *testing.T) {
+	t.Setenv("SECRET_TEST", "from env")
+	dir := t.TempDir()
+	private := filepath.Join(dir, "private")
+	if err := os.WriteFile(private, []byte("from file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		s     Secret
+		value string
+	}{
+		{"public", "public"},
+		{"env:SECRET_TEST", "from env"},
+		{Secret("file:" + private), "from file"},
+	} {
+		if v, err := c.s.Value(); err != nil || v != c.value {
+			t.Errorf("%s: got %q, %v", c.s, v, err)
+		}
+	}
+	if _, err := Secret("env:SECRET_MISSING").Value(); err == nil || err.Error() != "secret env:SECRET_MISSING: not set" {
+		t.Errorf("missing env: got %v", err)
+	}
+	if _, err := Secret("file:" + filepath.Join(dir, "missing")).Value(); err == nil {
+		t.Error("missing file: expected an error")
+	}
+}
+
+func TestSecretFilePermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no Unix permissions")
+	}
+	name := filepath.Join(t.TempDir(), "shared")
+	if err := os.WriteFile(name, []byte("s3cret"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	_, err := Secret("file:" + name).Value()
+	if err == nil || !strings.Contains(err.Error(), "open to group or others") {
+		t.Errorf("got %v", err)
+	}
+	if err != nil && strings.Contains(err.Error(), "s3cret") {
+		t.Errorf("error shows the secret: %v", err)
+	}
+}
+
+func TestSecretExec(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no echo program")
+	}
+	if v, err := Secret("exec:echo s3cret").Value(); err != nil || v != "s3cret" {
+		t.Errorf("got %q, %v", v, err)
+	}
+	if _, err := Secret("exec:true").Value(); err == nil || !strings.Contains(err.Error(), "no output") {
+		t.Errorf("no output: got %v", err)
+	}
+	if _, err := Secret("exec:false").Value(); err == nil {
+		t.Error("failing helper: expected an error")
+	}
+	if _, err := Secret("exec:").Value(); err == nil {
+		t.Error("no command: expected an error")
+	}
+}
+
+func TestSecretExecCached(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no sh")
+	}
+	clock := NewFakeClock(time.Unix(0, 0))
+	defer func(c Clock) { SecretClock = c }(SecretClock)
+	SecretClock = clock
+	dir := t.TempDir()
+	helper := filepath.Join(dir, "helper")
+	script := "#!/bin/sh\necho run >> " + filepath.Join(dir, "runs") + "\necho s3cret\n"
+	if err := os.WriteFile(helper, []byte(script), 0700); err != nil {
+		t.Fatal(err)
+	}
+	runs := func() int {
+		b, _ := os.ReadFile(filepath.Join(dir, "runs"))
+		return strings.Count(string(b), "run")
+	}
+	s := Secret("exec:" + helper)
+	for i := 0; i < 3; i++ {
+		if v, err := s.Value(); err != nil || v != "s3cret" {
+			t.Fatalf("got %q, %v", v, err)
+		}
+	}
+	if n := runs(); n != 1 {
+		t.Errorf("helper ran %d times, expected once", n)
+	}
+	clock.Advance(SecretTTL)
+	s.Value()
+	if n := runs(); n != 2 {
+		t.Errorf("helper ran %d times after the TTL, expected twice", n)
+	}
+}
+
+func TestSecretRedacted(t *testing.T) {
+	config := struct {
+		User      string
+		Password  Secret
+		Community Secret
+		Empty     Secret
+	}{"monitor", "s3cret", "env:SNMP_COMMUNITY", ""}
+	for _, s := range []string{
+		fmt.Sprint(config.Password),
+		fmt.Sprintf("%s %v %+v %#v", config, config, config, config),
+	} {
+		if strings.Contains(s, "s3cret") {
+			t.Errorf("secret in %s", s)
+		}
+	}
+	b, err := json.Marshal(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"User":"monitor","Password":"\u003credacted\u003e","Community":"env:SNMP_COMMUNITY","Empty":""}`
+	if string(b) != expected {
+		t.Errorf("got %s, expected %s", b, expected)
+	}
+	var back struct{ Community Secret }
+	if err := json.Unmarshal(b, &back); err != nil || back.Community != "env:SNMP_COMMUNITY" {
+		t.Errorf("unmarshal: got %q, %v", back.Community, err)
+	}
+}
@@ -6,20 +6,14 @@ import (
 	"bufio"
 	"os"
 	"strings"
-	"sync"
-	"time"
 	"unicode"
 	"unicode/utf8"
 )
 
 var (
-	// Hostname is the machine's hostname.
-	Hostname string
 	// FullHostname will, if false, uses the hostname upto the first ".". Run Set()
 	// manually after changing.
 	FullHostname bool
-	timestamp    = time.Now().Unix()
-	tlock        sync.Mutex
 )
 
 // Clean cleans a hostname based on the current FullHostname setting.
@@ -30,37 +24,13 @@ func Clean(s string) string {
 	return strings.ToLower(s)
 }
 
-// Set sets Hostntame based on the current preferences.
-func Set() {
-	h, err := os.Hostname()
-	if err == nil {
-		if !FullHostname {
-			h = strings.SplitN(h, ".", 2)[0]
-		}
-	} else {
-		h = "unknown"
-	}
-	Hostname = Clean(h)
-}
-
 func init() {
 	Set()
-
-	// timestamp
-	go func() {
-		for t := range time.Tick(time.Second) {
-			tlock.Lock()
-			timestamp = t.Unix()
-			tlock.Unlock()
-		}
-	}()
 }
 
-func Now() (t int64) {
-	tlock.Lock()
-	t = timestamp
-	tlock.Unlock()
-	return
+// Now returns the current Unix time of SystemClock.
+func Now() int64 {
+	return SystemClock.Now().Unix()
 }
 
 // IsDigit returns true if s consists of decimal digits.